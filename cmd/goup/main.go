@@ -1,6 +1,9 @@
 package main
 
 import (
+	"errors"
+	"os"
+
 	"github.com/owenthereal/goup/internal/commands"
 	"github.com/sirupsen/logrus"
 )
@@ -8,6 +11,10 @@ import (
 func main() {
 	rootCmd := commands.NewCommand()
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		logrus.Fatal(err)
 	}
 }
@@ -99,6 +99,20 @@ func TestGoup(t *testing.T) {
 		}
 	})
 
+	t.Run("goup verify 1.15.2", func(t *testing.T) {
+		cmd := exec.Command(goupBin, "verify", "1.15.2")
+		execCmd(t, cmd)
+	})
+
+	t.Run("goup ls -l 1.15.2", func(t *testing.T) {
+		cmd := exec.Command(goupBin, "list", "-l")
+		out := execCmd(t, cmd)
+
+		if want, got := []byte(commands.GetGoHost()), out; !bytes.Contains(got, want) {
+			t.Fatalf("goup list -l failed: want=%s got=%s", want, out)
+		}
+	})
+
 	t.Run("goup install 1.15.3", func(t *testing.T) {
 		cmd := exec.Command(goupBin, "install", "1.15.3")
 		execCmd(t, cmd)
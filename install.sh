@@ -30,7 +30,9 @@ main() {
   esac
 
   local _url="${GOUP_UPDATE_ROOT}/${_arch}${_ext}"
-  local _dir="$HOME/.go/bin"
+  goup_home
+  local _home="$RETVAL"
+  local _dir="${_home}/bin"
   local _file="${_dir}/goup${_ext}"
 
   ensure mkdir -p "$_dir"
@@ -42,6 +44,9 @@ main() {
     exit 1
   fi
 
+  # goup init finds its home in GOUP_HOME as well.
+  export GOUP_HOME="$_home"
+
   local _is_tty=true
   for f in "$@"
   do
@@ -60,6 +65,21 @@ main() {
   return "$_retval"
 }
 
+# The directory goup and Go are installed in, like goup finds it: GOUP_HOME
+# if set, $HOME/.go if goup is set up there, then $XDG_DATA_HOME/goup if
+# XDG_DATA_HOME is set, and $HOME/.go otherwise.
+goup_home() {
+  if [ -n "${GOUP_HOME:-}" ]; then
+    RETVAL="$GOUP_HOME"
+  elif [ -e "$HOME/.go/env" ] || [ -L "$HOME/.go/current" ]; then
+    RETVAL="$HOME/.go"
+  elif [ -n "${XDG_DATA_HOME:-}" ]; then
+    RETVAL="$XDG_DATA_HOME/goup"
+  else
+    RETVAL="$HOME/.go"
+  fi
+}
+
 # This is just for indicating that commands' results are being
 # intentionally ignored. Usually, because it's being executed
 # as part of error handling.
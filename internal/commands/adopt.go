@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	adoptCmdCopyFlag   bool
+	adoptCmdDryRunFlag bool
+)
+
+// errAdopted is returned by adoptGoroot for a GOROOT that is already
+// known to goup.
+var errAdopted = errors.New("already adopted")
+
+func adoptCmd() *cobra.Command {
+	adoptCmd := &cobra.Command{
+		Use:   "adopt [GOROOT]...",
+		Short: "Adopt Go installed outside of goup",
+		Long: `Adopt Go installations that were not installed by goup, such as /usr/local/go,
+the Go of the distribution or the go on PATH, so that they can be used with set,
+list and exec. Without GOROOTs, the usual install locations are searched. The
+version is read from the VERSION file of each GOROOT. Installations are
+registered in place like with goup link unless --copy is set, which copies them
+into the goup home.`,
+		Example: `
+  goup adopt --dry-run
+  goup adopt
+  goup adopt --copy /usr/local/go
+`,
+		RunE: runAdopt,
+	}
+
+	adoptCmd.PersistentFlags().BoolVar(&adoptCmdCopyFlag, "copy", false, "copy the installations into the goup home instead of registering them in place")
+	adoptCmd.PersistentFlags().BoolVar(&adoptCmdDryRunFlag, "dry-run", false, "print the installations that would be adopted without adopting them")
+
+	return adoptCmd
+}
+
+func runAdopt(cmd *cobra.Command, args []string) error {
+	goroots := args
+	if len(goroots) == 0 {
+		goroots = findSystemGoroots()
+		if len(goroots) == 0 {
+			logger.Printf("No Go installed outside of goup is found")
+			return nil
+		}
+	}
+
+	for _, goroot := range goroots {
+		if _, err := adoptGoroot(goroot, adoptCmdCopyFlag, adoptCmdDryRunFlag); err != nil && !errors.Is(err, errAdopted) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// adoptGoroot registers goroot under the version in its VERSION file, or
+// copies it into the goup home if copy is set. It returns the name of the
+// version directory.
+func adoptGoroot(goroot string, copy, dryRun bool) (string, error) {
+	goroot, err := filepath.Abs(goroot)
+	if err != nil {
+		return "", err
+	}
+	if !isGoroot(goroot) {
+		return "", fmt.Errorf("%s is not a GOROOT", goroot)
+	}
+
+	ver, err := readGorootVersion(goroot)
+	if err != nil {
+		return "", err
+	}
+
+	dir := goupVersionDir(ver)
+	if _, err := os.Lstat(dir); err == nil {
+		if md, err := readInstallMetadata(dir); sameDir(dir, goroot) || (err == nil && md.URL == goroot) {
+			logger.Printf("%s in %s is already adopted", ver, goroot)
+		} else {
+			logger.Warnf("%s in %s is not adopted, %s is already installed in %s", ver, goroot, ver, dir)
+		}
+		return ver, errAdopted
+	}
+
+	if dryRun {
+		if copy {
+			fmt.Printf("Would copy %s in %s to %s\n", ver, goroot, dir)
+		} else {
+			fmt.Printf("Would register %s in %s\n", ver, goroot)
+		}
+		return ver, nil
+	}
+
+	if err := os.MkdirAll(GoupDir(), 0755); err != nil {
+		return "", err
+	}
+
+	if !copy {
+		if err := os.Symlink(goroot, dir); err != nil {
+			return "", err
+		}
+		logger.Printf("Registered %s in %s", ver, goroot)
+		return ver, nil
+	}
+
+	logger.Printf("Copying %s in %s to %s ...", ver, goroot, dir)
+	if err := copyGoroot(goroot, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to copy %s: %v", goroot, err)
+	}
+
+	md := newInstallMetadata(ver)
+	md.URL = goroot
+	if err := setInstalled(dir, md); err != nil {
+		return "", err
+	}
+	logger.Printf("Copied %s to %s", ver, dir)
+
+	return ver, nil
+}
+
+// copyGoroot copies the GOROOT src to dst. Unlike copyDir, it resolves the
+// symlinks pointing outside of src, such as those of the Go of Debian from
+// /usr/lib/go-1.xx to /usr/share/go-1.xx, so that the copy doesn't depend
+// on the original installation.
+func copyGoroot(src, dst string) error {
+	root, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+
+	return copyResolved(root, dst, root, dst, nil)
+}
+
+// copyResolved copies the directory dir, which is root or a directory
+// outside of it, to target. Symlinks resolving into root are pointed to the
+// same file in dstRoot, the others are replaced by what they point to.
+// parents are the directories outside of root that are being copied.
+func copyResolved(dir, target, root, dstRoot string, parents []string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(target, rel)
+
+		switch {
+		case d.IsDir():
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			// Keep directories writable to copy their files.
+			return os.MkdirAll(dst, fi.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink == 0:
+			return copyFile(path, dst)
+		}
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			logger.Debugf("keeping the broken link %s: %v", path, err)
+			return copyFile(path, dst)
+		}
+		if isSubdir(root, resolved) {
+			rel, err := filepath.Rel(root, resolved)
+			if err != nil {
+				return err
+			}
+			link, err := filepath.Rel(filepath.Dir(dst), filepath.Join(dstRoot, rel))
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dst)
+		}
+
+		fi, err := os.Stat(resolved)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return copyFile(resolved, dst)
+		}
+		if slices.ContainsFunc(parents, func(p string) bool { return isSubdir(resolved, p) }) {
+			return fmt.Errorf("%s links to its parent directory %s", path, resolved)
+		}
+		return copyResolved(resolved, dst, root, dstRoot, append(parents, resolved))
+	})
+}
+
+// readGorootVersion returns the version in the VERSION file of goroot,
+// e.g. go1.22.0.
+func readGorootVersion(goroot string) (string, error) {
+	f, err := os.Open(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the version of %s: %v", goroot, err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("the VERSION file of %s is empty", goroot)
+	}
+
+	ver := strings.TrimSpace(s.Text())
+	if !strings.HasPrefix(ver, "go") || strings.ContainsAny(ver, ` /\`) {
+		return "", fmt.Errorf("the VERSION file of %s has no release version: %q", goroot, ver)
+	}
+
+	return ver, nil
+}
+
+// findSystemGoroots returns the GOROOTs of the Go installations in the
+// usual install locations and of the go on PATH, without those of goup.
+func findSystemGoroots() []string {
+	candidates := []string{
+		"/usr/local/go",
+		"/usr/lib/go",
+		"/usr/lib/golang",
+		"/usr/local/opt/go/libexec",
+		"/opt/homebrew/opt/go/libexec",
+		"/snap/go/current",
+	}
+	if matches, err := filepath.Glob("/usr/lib/go-*"); err == nil {
+		candidates = append(candidates, matches...)
+	}
+	if runtime.GOOS == "windows" {
+		candidates = []string{filepath.Join(os.Getenv("ProgramFiles"), "Go")}
+	}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || isGoupPathDir(dir) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, goExe())); err == nil {
+			if bin, err := filepath.EvalSymlinks(filepath.Join(dir, goExe())); err == nil {
+				candidates = append(candidates, filepath.Dir(filepath.Dir(bin)))
+			}
+		}
+	}
+
+	var goroots []string
+	seen := make(map[string]bool)
+	for _, dir := range candidates {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil || seen[real] || !isGoroot(real) || isGoupDir(real) {
+			continue
+		}
+		seen[real] = true
+
+		if _, err := os.Stat(filepath.Join(real, "VERSION")); err != nil {
+			logger.Debugf("skipping %s without a VERSION file", real)
+			continue
+		}
+		goroots = append(goroots, dir)
+	}
+
+	return goroots
+}
+
+// isGoupDir reports whether dir is in the goup home or the shared store.
+func isGoupDir(dir string) bool {
+	for _, s := range versionStores() {
+		if real, err := filepath.EvalSymlinks(s.Dir); err == nil && isSubdir(real, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDir reports whether a and b resolve to the same directory.
+func sameDir(a, b string) bool {
+	ra, err := filepath.EvalSymlinks(a)
+	if err != nil {
+		return false
+	}
+	rb, err := filepath.EvalSymlinks(b)
+	return err == nil && ra == rb
+}
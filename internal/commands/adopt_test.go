@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadGorootVersion(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"go1.22.0\ntime 2024-02-01T19:52:11Z\n", "go1.22.0", false},
+		{"go1.21.6", "go1.21.6", false},
+		{"  go1.20rc1  \n", "go1.20rc1", false},
+		{"devel go1.23-a3e2a7e Mon Jan 1 00:00:00 2024 +0000\n", "", true},
+		{"go1.22/../../x\n", "", true},
+		{"1.22.0\n", "", true},
+		{"", "", true},
+	} {
+		goroot := t.TempDir()
+		if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := readGorootVersion(goroot)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("readGorootVersion() with VERSION %q = %q, %v, want %q", tt.content, got, err, tt.want)
+		}
+	}
+
+	if _, err := readGorootVersion(t.TempDir()); err == nil {
+		t.Error("readGorootVersion() without VERSION succeeded")
+	}
+}
+
+// TestCopyGoroot copies a GOROOT laid out like the Go of Debian, where
+// /usr/lib/go-1.xx links to files in /usr/share/go-1.xx.
+func TestCopyGoroot(t *testing.T) {
+	usr := t.TempDir()
+	lib := filepath.Join(usr, "lib", "go-1.22")
+	share := filepath.Join(usr, "share", "go-1.22")
+
+	for name, content := range map[string]string{
+		"lib/go-1.22/bin/go":             "go",
+		"share/go-1.22/VERSION":          "go1.22.0",
+		"share/go-1.22/src/fmt/print.go": "package fmt",
+	} {
+		file := filepath.Join(usr, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(lib, "VERSION"):     "../../share/go-1.22/VERSION",
+		filepath.Join(lib, "src"):         filepath.Join(share, "src"),
+		filepath.Join(lib, "gofmt"):       "bin/go",
+		filepath.Join(share, "src", "go"): filepath.Join(lib, "bin", "go"),
+		filepath.Join(usr, "lib", "go"):   "go-1.22",
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(t.TempDir(), "go1.22.0")
+	if err := copyGoroot(filepath.Join(usr, "lib", "go"), dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"bin/go":           "go",
+		"VERSION":          "go1.22.0",
+		"src/fmt/print.go": "package fmt",
+	} {
+		file := filepath.Join(dst, filepath.FromSlash(name))
+		fi, err := os.Lstat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.Mode().IsRegular() {
+			t.Errorf("%s is not a regular file: %v", name, fi.Mode())
+		}
+		if b, err := os.ReadFile(file); err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", name, b, err, want)
+		}
+	}
+
+	// Links into the GOROOT point into the copy.
+	for name, want := range map[string]string{
+		"gofmt":  "bin/go",
+		"src/go": filepath.Join("..", "bin", "go"),
+	} {
+		got, err := os.Readlink(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s points to %s, want %s", name, got, want)
+		}
+	}
+
+	realDst, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outside []string
+	filepath.Walk(dst, func(path string, fi os.FileInfo, err error) error {
+		if real, err := filepath.EvalSymlinks(path); err != nil || !isSubdir(realDst, real) {
+			outside = append(outside, path)
+		}
+		return nil
+	})
+	if len(outside) > 0 {
+		t.Errorf("the copy links outside of it: %v", outside)
+	}
+}
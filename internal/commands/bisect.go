@@ -0,0 +1,253 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/owenthereal/goup/internal/entity"
+	"github.com/owenthereal/goup/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// bisectVersion is the tip worktree that goup bisect checks out and builds
+// the commits in.
+const bisectVersion = tipVersion + "-bisect"
+
+var (
+	bisectCmdGoodFlag string
+	bisectCmdBadFlag  string
+)
+
+func bisectCmd() *cobra.Command {
+	bisectCmd := &cobra.Command{
+		Use:   "bisect",
+		Short: "Find the Go commit that broke your code",
+		Long: `Find the Go commit that broke your code with git bisect in the Go development
+tree. Every commit is built from source, and the builds are cached.`,
+	}
+
+	bisectCmd.AddCommand(bisectStartCmd())
+	bisectCmd.AddCommand(bisectResetCmd())
+
+	return bisectCmd
+}
+
+func bisectStartCmd() *cobra.Command {
+	startCmd := &cobra.Command{
+		Use:   "start --good <REF> --bad <REF> -- <COMMAND> [ARGS]...",
+		Short: "Bisect Go commits with a test command",
+		Long: `Bisect the Go commits between a good and a bad release, branch or commit. At
+each step, the commit is built and the command is run with GOROOT and PATH set
+up for it. As with git bisect run, exit code 0 marks the commit good, 125
+skips it, and any other code below 128 marks it bad. Commits that fail to
+build are skipped.`,
+		Example: `
+  goup bisect start --good go1.21.0 --bad go1.22.0 -- ./test.sh
+  goup bisect start --good 1.21.0 --bad master -- go test ./...
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runBisectStart,
+	}
+
+	startCmd.PersistentFlags().StringVar(&bisectCmdGoodFlag, "good", "", "release, branch or commit that works")
+	startCmd.PersistentFlags().StringVar(&bisectCmdBadFlag, "bad", "", "release, branch or commit that is broken")
+
+	return startCmd
+}
+
+func bisectResetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset",
+		Short: "Clean up an interrupted bisect",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo := tipRepo{dir: goupVersionDir(bisectVersion)}
+			if _, err := os.Stat(repo.dir); os.IsNotExist(err) {
+				return nil
+			}
+			return repo.git("bisect", "reset")
+		},
+	}
+}
+
+func runBisectStart(cmd *cobra.Command, args []string) error {
+	if bisectCmdGoodFlag == "" || bisectCmdBadFlag == "" {
+		return errors.New("both --good and --bad must be specified")
+	}
+	if !git.Installed() {
+		return errors.New("bisecting requires the git command")
+	}
+
+	repo, err := ensureTipWorktree(bisectVersion)
+	if err != nil {
+		return err
+	}
+
+	// Bisecting needs the history between the good and the bad commit,
+	// which the shallow clone of tip doesn't have.
+	if out, err := repo.gitOutput("rev-parse", "--is-shallow-repository"); err == nil && strings.TrimSpace(string(out)) == "true" {
+		logger.Printf("Fetching the history of the go development tree...")
+		if err := repo.git("fetch", "--unshallow", "origin"); err != nil {
+			return fmt.Errorf("failed to fetch the history: %v", err)
+		}
+	}
+
+	good, err := fetchBisectRef(repo, bisectCmdGoodFlag)
+	if err != nil {
+		return err
+	}
+	bad, err := fetchBisectRef(repo, bisectCmdBadFlag)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.git("reset", "-q", "--hard"); err != nil {
+		return fmt.Errorf("failed to reset git repository: %v", err)
+	}
+	if err := repo.git("bisect", "start", bad, good); err != nil {
+		return fmt.Errorf("failed to start bisecting: %v", err)
+	}
+	defer func() {
+		if _, err := repo.gitOutput("bisect", "reset"); err != nil {
+			logger.Debugf("failed to reset bisect: %v", err)
+		}
+	}()
+
+	tested := make(map[string]bool)
+	for {
+		head, err := repo.gitOutput("rev-parse", "HEAD")
+		if err != nil {
+			return fmt.Errorf("failed to read the commit to test: %v", err)
+		}
+		commit := strings.TrimSpace(string(head))
+		// git bisect moves on to another commit after each step, unless
+		// it is done with a result the messages below didn't recognize.
+		if tested[commit] {
+			return fmt.Errorf("git bisect didn't move on from %s, see `git bisect log` in %s", shortCommit(commit), repo.dir)
+		}
+		tested[commit] = true
+
+		verdict, err := bisectStep(repo, commit, args)
+		if err != nil {
+			return err
+		}
+
+		c := repo.bisectCommand(verdict)
+		c.Stderr = os.Stderr
+		out, err := c.Output()
+		os.Stdout.Write(out)
+		switch {
+		case bytes.Contains(out, []byte("is the first bad commit")):
+			return nil
+		case bytes.Contains(out, []byte("only 'skip'ped commits left")):
+			return errors.New("the first bad commit could not be determined, because commits in between were skipped")
+		case err != nil:
+			return fmt.Errorf("failed to mark %s %s: %v", shortCommit(commit), verdict, err)
+		}
+	}
+}
+
+// bisectCommand returns the git bisect command with args. Its messages are
+// not translated, so that the end of the bisect can be recognized.
+func (r tipRepo) bisectCommand(args ...string) *exec.Cmd {
+	cmd := r.command(append([]string{"bisect"}, args...)...)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "LC_ALL=C")
+	return cmd
+}
+
+// fetchBisectRef fetches the release, branch or commit ref and returns its
+// commit hash. Releases may be given without the "go" prefix.
+func fetchBisectRef(repo tipRepo, ref string) (string, error) {
+	if entity.ValidVersion(ref) && !isCommitHash(ref) && !strings.HasPrefix(ref, "go") {
+		ref = "go" + ref
+	}
+
+	logger.Printf("Fetching %v...", ref)
+	commit, err := repo.vcs().Fetch("origin", ref, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %v", ref, err)
+	}
+
+	return commit, nil
+}
+
+// bisectStep builds the checked out commit, runs the command with it and
+// returns the git bisect verdict: good, bad or skip.
+func bisectStep(repo tipRepo, commit string, command []string) (string, error) {
+	logger.Printf("Testing %s ...", shortCommit(commit))
+	if err := buildBisectCommit(repo, commit); err != nil {
+		logger.Warnf("Skipping %s, it failed to build: %v", shortCommit(commit), err)
+		return "skip", nil
+	}
+
+	c := exec.Command(command[0], command[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"GOROOT="+repo.dir,
+		"PATH="+filepath.Join(repo.dir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "good", nil
+	case errors.As(err, &exitErr):
+		switch code := exitErr.ExitCode(); {
+		case code == 125:
+			return "skip", nil
+		case code > 0 && code < 128:
+			return "bad", nil
+		}
+	}
+
+	return "", fmt.Errorf("bisect aborted, %s failed: %v", command[0], err)
+}
+
+// buildBisectCommit builds the checked out commit, or restores its cached
+// build output if it has been built before.
+func buildBisectCommit(repo tipRepo, commit string) error {
+	if err := repo.clean(cleanForce); err != nil {
+		return err
+	}
+
+	key := buildCacheKey(commit, nil, nil)
+	if ok, err := restoreBuild(key, repo.dir); err != nil {
+		logger.Debugf("failed to restore the build of %s: %v", commit, err)
+	} else if ok {
+		logger.Printf("Using the cached build of %s", shortCommit(commit))
+		return nil
+	}
+
+	version, err := sourceTreeVersion(repo.dir)
+	if err != nil {
+		return err
+	}
+	bootstrap, err := ensureBootstrap(version)
+	if err != nil {
+		return err
+	}
+	if err := buildSource(repo.dir, bootstrap, nil); err != nil {
+		return err
+	}
+
+	files, err := repo.buildOutput()
+	if err == nil {
+		err = saveBuild(repo.dir, files, key)
+	}
+	if err != nil {
+		logger.Warnf("failed to cache the build of %s: %v", shortCommit(commit), err)
+	}
+
+	return nil
+}
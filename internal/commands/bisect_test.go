@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBisectCommand(t *testing.T) {
+	dir, commits := newGitCheckout(t, "x", "y", "z")
+	repo := tipRepo{dir: dir}
+	t.Setenv("LANG", "de_DE.UTF-8")
+	t.Setenv("LANGUAGE", "de")
+
+	c := repo.bisectCommand("start", commits[2], commits[0])
+	if !slices.Contains(c.Env, "LC_ALL=C") {
+		t.Fatalf("git bisect runs with %v, want LC_ALL=C", c.Env)
+	}
+	if out, err := c.CombinedOutput(); err != nil {
+		t.Fatalf("git bisect start: %v\n%s", err, out)
+	}
+	t.Cleanup(func() { repo.bisectCommand("reset").Run() })
+
+	out, err := repo.bisectCommand("bad").Output()
+	if err != nil {
+		t.Fatalf("git bisect bad: %v", err)
+	}
+	if !strings.Contains(string(out), commits[1]+" is the first bad commit") {
+		t.Errorf("git bisect bad = %s, want %s as the first bad commit", out, commits[1])
+	}
+}
@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/owenthereal/goup/internal/entity"
+)
+
+// defaultBuildCacheSize is the size the build cache is pruned to after
+// each build, unless GOUP_BUILD_CACHE_SIZE is set.
+const defaultBuildCacheSize = 10 << 30
+
+// buildCacheKey returns the key the build output of a Go source tree is
+// cached under. source identifies the tree, e.g. a commit hash, and the
+// patches applied to it and the environment of the build are part of the
+// key as well.
+func buildCacheKey(source string, patches []patchMetadata, env []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "platform %s\n", entity.HostPlatform())
+	fmt.Fprintf(h, "source %s\n", source)
+	for _, p := range patches {
+		fmt.Fprintf(h, "patch %s\n", p.Sha256)
+	}
+	for _, kv := range env {
+		fmt.Fprintf(h, "env %s\n", kv)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// buildCacheDir returns the directory of the build cache, or the directory
+// the build output with key is cached in.
+func buildCacheDir(key ...string) string {
+	return GoupDir(append([]string{"cache", "builds"}, key...)...)
+}
+
+// buildCacheSize returns the size the build cache is pruned to, set with
+// the GOUP_BUILD_CACHE_SIZE environment variable.
+func buildCacheSize() int64 {
+	if s := os.Getenv("GOUP_BUILD_CACHE_SIZE"); s != "" {
+		n, err := parseSize(s)
+		if err == nil {
+			return n
+		}
+		logger.Warnf("ignoring GOUP_BUILD_CACHE_SIZE: %v", err)
+	}
+	return defaultBuildCacheSize
+}
+
+// buildOutput returns the build output of the checkout, i.e. the files git
+// ignores such as bin and pkg.
+func (r tipRepo) buildOutput() ([]string, error) {
+	files, err := r.vcs().IgnoredFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the build output: %v", err)
+	}
+	return files, nil
+}
+
+// sourceBuildOutput returns the build output of the Go source tree in root
+// that was unpacked from the source archive with the manifest m.
+func sourceBuildOutput(root string, m manifest) ([]string, error) {
+	unpacked := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		unpacked[f.Path] = true
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !unpacked[rel] && !isGoupFile(rel, m) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// saveBuild copies the build output files in root to the build cache under
+// key, and prunes the build cache.
+func saveBuild(root string, files []string, key string) error {
+	dir := buildCacheDir(key)
+
+	// Copy to a temporary directory first so that an interrupted copy is
+	// never mistaken for a complete build.
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	for _, f := range files {
+		rel := filepath.FromSlash(f)
+		if err := copyFile(filepath.Join(root, rel), filepath.Join(tmp, rel)); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return err
+	}
+	touchBuildCache(key)
+
+	// Keep the build that was just saved, even if it alone is larger than
+	// the cache.
+	evicted, err := pruneBuildCache(buildCacheSize(), key)
+	for _, e := range evicted {
+		logger.Debugf("Evicted %s (%s) from the build cache", e.Key, formatSize(e.Size))
+	}
+	return err
+}
+
+// restoreBuild copies the build output cached under key into root. It
+// reports false if nothing is cached under key.
+func restoreBuild(key, root string) (bool, error) {
+	dir := buildCacheDir(key)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(root, rel))
+	})
+	if err != nil {
+		return false, err
+	}
+
+	touchBuildCache(key)
+	return true, nil
+}
+
+// touchBuildCache marks the entry key as used. The modification time of an
+// entry is its last use.
+func touchBuildCache(key string) {
+	now := time.Now()
+	if err := os.Chtimes(buildCacheDir(key), now, now); err != nil {
+		logger.Debugf("failed to mark %s as used: %v", key, err)
+	}
+}
+
+// buildCacheEntry is a build output in the build cache.
+type buildCacheEntry struct {
+	Key      string
+	Size     int64
+	LastUsed time.Time
+}
+
+func listBuildCache() ([]buildCacheEntry, error) {
+	files, err := os.ReadDir(buildCacheDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []buildCacheEntry
+	for _, f := range files {
+		// Temporary directories of copies to the cache are not entries.
+		if !f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		fi, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		size, err := dirSize(buildCacheDir(f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, buildCacheEntry{
+			Key:      f.Name(),
+			Size:     size,
+			LastUsed: fi.ModTime(),
+		})
+	}
+
+	return entries, nil
+}
+
+// pruneBuildCache removes the least recently used entries from the build
+// cache until it is no larger than max bytes, and returns them. The entries
+// in keep are never removed.
+func pruneBuildCache(max int64, keep ...string) ([]buildCacheEntry, error) {
+	entries, err := listBuildCache()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+
+	var evicted []buildCacheEntry
+	for _, e := range entries {
+		if total <= max {
+			break
+		}
+		if slices.Contains(keep, e.Key) {
+			continue
+		}
+		if err := os.RemoveAll(buildCacheDir(e.Key)); err != nil {
+			return evicted, err
+		}
+		total -= e.Size
+		evicted = append(evicted, e)
+	}
+
+	return evicted, nil
+}
+
+// parseSize parses a size in bytes with an optional binary unit suffix,
+// e.g. 500M, 10G or 10GiB.
+func parseSize(s string) (int64, error) {
+	num := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B"), "I")
+
+	shift := 0
+	if i := strings.IndexAny(num, "KMGT"); i >= 0 && i == len(num)-1 {
+		shift = 10 * (strings.IndexByte("KMGT", num[i]) + 1)
+		num = num[:i]
+	}
+
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
+
+// copyFile copies the regular file or symlink src to dst, creating the
+// parent directories of dst.
+func copyFile(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	os.Remove(dst)
+
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
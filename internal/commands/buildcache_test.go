@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"1K", 1 << 10},
+		{"500M", 500 << 20},
+		{"500MiB", 500 << 20},
+		{"500mb", 500 << 20},
+		{"10G", 10 << 30},
+		{"10GiB", 10 << 30},
+		{" 2T ", 2 << 40},
+	} {
+		got, err := parseSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "B", "G", "-1", "1.5G", "10X", "G10", "10GG"} {
+		if got, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+// writeBuildCacheEntry adds an entry of size bytes used at lastUsed to the
+// build cache.
+func writeBuildCacheEntry(t *testing.T, key string, size int, lastUsed time.Time) {
+	t.Helper()
+
+	dir := buildCacheDir(key)
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "go"), make([]byte, size), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir, lastUsed, lastUsed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneBuildCache(t *testing.T) {
+	setTestGoupHome(t)
+
+	now := time.Now()
+	writeBuildCacheEntry(t, "old", 100, now.Add(-2*time.Hour))
+	writeBuildCacheEntry(t, "older", 100, now.Add(-3*time.Hour))
+	writeBuildCacheEntry(t, "new", 300, now)
+	// An interrupted copy to the cache.
+	writeBuildCacheEntry(t, "new.tmp", 1000, now)
+
+	entries, err := listBuildCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("listBuildCache = %v, want 3 entries without new.tmp", entries)
+	}
+
+	// The new entry alone is larger than the cache, but kept.
+	evicted, err := pruneBuildCache(200, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range evicted {
+		keys = append(keys, e.Key)
+	}
+	if len(keys) != 2 || keys[0] != "older" || keys[1] != "old" {
+		t.Errorf("pruneBuildCache evicted %v, want [older old]", keys)
+	}
+	if _, err := os.Stat(buildCacheDir("new")); err != nil {
+		t.Errorf("the kept entry was removed: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var cachePruneCmdMaxSizeFlag string
+
+func cacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the build cache",
+		Long: `Manage the cache of build outputs of Go built from source. Builds of the same
+commit, patches and settings are restored from the cache instead of building
+them again.`,
+	}
+
+	cacheCmd.AddCommand(cachePruneCmd())
+
+	return cacheCmd
+}
+
+func cachePruneCmd() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Evict the least recently used builds",
+		Long: `Evict the least recently used builds from the build cache until it fits the
+maximum size. The cache is also pruned after each build to the size set with
+the GOUP_BUILD_CACHE_SIZE environment variable, 10GiB by default.`,
+		Example: `
+  goup cache prune
+  goup cache prune --max-size 2GiB
+  goup cache prune --max-size 0 # Empty the cache
+`,
+		Args: cobra.NoArgs,
+		RunE: runCachePrune,
+	}
+
+	pruneCmd.PersistentFlags().StringVar(&cachePruneCmdMaxSizeFlag, "max-size", "", "size to prune the cache to, e.g. 500MiB or 2GiB, defaults to GOUP_BUILD_CACHE_SIZE")
+
+	return pruneCmd
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	max := buildCacheSize()
+	if cachePruneCmdMaxSizeFlag != "" {
+		var err error
+		if max, err = parseSize(cachePruneCmdMaxSizeFlag); err != nil {
+			return err
+		}
+	}
+
+	evicted, err := pruneBuildCache(max)
+
+	var freed int64
+	for _, e := range evicted {
+		logger.Debugf("Evicted %s, last used %s", e.Key, e.LastUsed.Format("2006-01-02 15:04"))
+		freed += e.Size
+	}
+	logger.Printf("Evicted %d builds, freed %s", len(evicted), formatSize(freed))
+
+	return err
+}
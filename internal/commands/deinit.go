@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deinitCmdPurgeFlag  bool
+	deinitCmdDryRunFlag bool
+	deinitCmdYesFlag    bool
+)
+
+func deinitCmd() *cobra.Command {
+	deinitCmd := &cobra.Command{
+		Use:     "deinit",
+		Aliases: []string{"implode"},
+		Short:   "Remove goup from the shell startup files",
+		Long: `Remove the lines goup added to the shell startup files and the env files, undoing
+goup init. Edited files are backed up next to them first. The installed Go
+versions and goup itself are kept unless --purge is set, which removes the
+whole goup home. A goup home that contains the home directory or doesn't look
+like one is never removed.`,
+		Example: `
+  goup deinit --dry-run
+  goup deinit
+  goup implode --purge --yes
+`,
+		Args: cobra.NoArgs,
+		RunE: runDeinit,
+	}
+
+	deinitCmd.PersistentFlags().BoolVar(&deinitCmdPurgeFlag, "purge", false, "also remove the goup home with all installed Go versions and goup itself")
+	deinitCmd.PersistentFlags().BoolVar(&deinitCmdDryRunFlag, "dry-run", false, "print the changes as a diff without making them")
+	deinitCmd.PersistentFlags().BoolVarP(&deinitCmdYesFlag, "yes", "y", false, "don't ask for confirmation")
+
+	return deinitCmd
+}
+
+func runDeinit(cmd *cobra.Command, args []string) error {
+	if deinitCmdPurgeFlag {
+		if err := checkPurgeable(GoupDir()); err != nil {
+			return err
+		}
+	}
+
+	var edits []fileEdit
+	for _, s := range shellEnvs() {
+		e, err := s.removalEdits()
+		if err != nil {
+			return err
+		}
+		edits = append(edits, e...)
+	}
+
+	changed := deinitCmdPurgeFlag
+	for _, e := range edits {
+		changed = changed || e.changed()
+	}
+	if !changed {
+		logger.Printf("goup is not set up in any shell startup file")
+		return nil
+	}
+
+	if deinitCmdDryRunFlag {
+		printEdits(edits)
+		if deinitCmdPurgeFlag {
+			fmt.Printf("Would remove %s\n", GoupDir())
+		}
+		return nil
+	}
+
+	if !deinitCmdYesFlag {
+		label := "Remove goup from the shell startup files"
+		if deinitCmdPurgeFlag {
+			label = fmt.Sprintf("Remove goup from the shell startup files and delete %s with all installed Go versions", GoupDir())
+		}
+
+		prompt := promptui.Prompt{
+			Label:     label,
+			IsConfirm: true,
+		}
+		if _, err := prompt.Run(); err != nil {
+			return fmt.Errorf("interrupted")
+		}
+	}
+
+	if err := applyEdits(edits); err != nil {
+		return err
+	}
+
+	if !deinitCmdPurgeFlag {
+		logger.Printf("Removed goup from the shell startup files, the installed Go versions are kept in %s", GoupDir())
+		return nil
+	}
+
+	// Tip worktrees are removed with their main clone.
+	if err := os.RemoveAll(GoupDir()); err != nil {
+		return err
+	}
+	logger.Printf("Removed goup and all installed Go versions in %s", GoupDir())
+
+	return nil
+}
+
+// checkPurgeable returns an error if the goup home dir must not be removed
+// as a whole. GOUP_HOME may point anywhere, so dir must look like a goup
+// home and must not contain the home directory.
+func checkPurgeable(dir string) error {
+	dir = filepath.Clean(dir)
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	home := homedir
+	if real, err := filepath.EvalSymlinks(home); err == nil {
+		home = real
+	}
+
+	if filepath.Dir(dir) == dir || isSubdir(dir, home) {
+		return fmt.Errorf("refusing to purge %s, it contains the home directory %s", dir, homedir)
+	}
+
+	for _, f := range []string{filepath.Join("bin", "goup"), filepath.Join("bin", "goup.exe"), "env", "current"} {
+		if _, err := os.Lstat(filepath.Join(dir, f)); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("refusing to purge %s, it doesn't look like a goup home", dir)
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPurgeable(t *testing.T) {
+	oldHomedir := homedir
+	t.Cleanup(func() { homedir = oldHomedir })
+	homedir = filepath.Join(t.TempDir(), "home", "u")
+
+	goupHome := filepath.Join(homedir, ".go")
+	writeTestFiles(t, goupHome, map[string]string{"env": ""})
+	binHome := filepath.Join(t.TempDir(), "goup")
+	writeTestFiles(t, binHome, map[string]string{"bin/goup": ""})
+	data := filepath.Join(homedir, "data")
+	writeTestFiles(t, data, map[string]string{"notes.txt": ""})
+	// A home directory that looks like a goup home.
+	writeTestFiles(t, homedir, map[string]string{"env": ""})
+
+	for _, tt := range []struct {
+		dir     string
+		wantErr bool
+	}{
+		{goupHome, false},
+		{binHome, false},
+		{data, true},
+		{filepath.Join(homedir, "missing"), true},
+		{homedir, true},
+		{filepath.Dir(homedir), true},
+		{"/", true},
+	} {
+		err := checkPurgeable(tt.dir)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkPurgeable(%s) = %v, want error %v", tt.dir, err, tt.wantErr)
+		}
+	}
+
+	// A link to the home directory is refused as well.
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(homedir, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPurgeable(link); err == nil {
+		t.Errorf("checkPurgeable(%s) linking to the home directory succeeded", link)
+	}
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns the lines 1 to n, with the lines in repl replaced
+// and the lines replaced by "" removed.
+func numberedLines(n int, repl map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if r, ok := repl[i]; ok {
+			if r != "" {
+				b.WriteString(r + "\n")
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		oldName  string
+		newName  string
+		old, new string
+		want     string
+	}{
+		{
+			name:    "unchanged",
+			oldName: "f", newName: "f",
+			old: "a\nb\n", new: "a\nb\n",
+			want: "",
+		},
+		{
+			name:    "insert only",
+			oldName: "f", newName: "f",
+			old: "a\nb\nc\n", new: "a\nb\nX\nc\n",
+			want: "--- f\n+++ f\n@@ -1,3 +1,4 @@\n a\n b\n+X\n c\n",
+		},
+		{
+			name:    "delete only",
+			oldName: "f", newName: "f",
+			old: "a\nb\nc\n", new: "a\nc\n",
+			want: "--- f\n+++ f\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name:    "new file",
+			oldName: "", newName: "f",
+			old: "", new: "x\ny\n",
+			want: "--- /dev/null\n+++ f\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name:    "removed file",
+			oldName: "f", newName: "",
+			old: "x\ny\n", new: "",
+			want: "--- f\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+		{
+			name:    "merged hunks",
+			oldName: "f", newName: "f",
+			old: numberedLines(20, nil), new: numberedLines(20, map[int]string{2: "two", 8: "eight"}),
+			want: "--- f\n+++ f\n@@ -1,11 +1,11 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n 11\n",
+		},
+		{
+			name:    "separate hunks",
+			oldName: "f", newName: "f",
+			old: numberedLines(20, nil), new: numberedLines(20, map[int]string{2: "two", 15: "fifteen"}),
+			want: "--- f\n+++ f\n@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -12,7 +12,7 @@\n 12\n 13\n 14\n-15\n+fifteen\n 16\n 17\n 18\n",
+		},
+		{
+			name:    "delete at the end",
+			oldName: "f", newName: "f",
+			old: numberedLines(10, nil), new: numberedLines(10, map[int]string{10: ""}),
+			want: "--- f\n+++ f\n@@ -7,4 +7,3 @@\n 7\n 8\n 9\n-10\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff(tt.oldName, tt.newName, tt.old, tt.new); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
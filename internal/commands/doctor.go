@@ -0,0 +1,314 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/owenthereal/goup/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorPingTimeout is how long goup doctor waits for the Go host.
+const doctorPingTimeout = 10 * time.Second
+
+var doctorCmdOfflineFlag bool
+
+// The severities of doctor findings. Only failures make goup doctor exit
+// with an error.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorFinding is the result of a check of goup doctor.
+type doctorFinding struct {
+	Severity string
+	Message  string
+	// Fix suggests how to solve the problem.
+	Fix string
+}
+
+func doctorCmd() *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the goup setup",
+		Long: `Check the setup of goup and the environment for problems that make another Go
+run than the one goup set: the env files and the shell startup files sourcing
+them, the order of the go commands in PATH, a GOROOT override, the current
+link, broken installs and leftover archives, and whether the Go host can be
+reached. Each problem is reported with a suggested fix. The archives of complete
+installs are kept for goup verify --repair, their total size is reported too.
+goup doctor exits with an error if any check fails.`,
+		Example: `
+  goup doctor
+  goup doctor --offline
+`,
+		Args: cobra.NoArgs,
+		RunE: runDoctor,
+	}
+
+	doctorCmd.PersistentFlags().BoolVar(&doctorCmdOfflineFlag, "offline", false, "skip checking that the Go host can be reached")
+
+	return doctorCmd
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	checks := []func() []doctorFinding{
+		checkEnvFiles,
+		checkPath,
+		checkGorootEnv,
+		checkCurrentLink,
+		checkVersionDirs,
+	}
+	if !doctorCmdOfflineFlag {
+		checks = append(checks, checkGoHost)
+	}
+
+	var failed int
+	for _, check := range checks {
+		for _, f := range check() {
+			fmt.Printf("%-5s %s\n", f.Severity, f.Message)
+			if f.Fix != "" {
+				fmt.Printf("      fix: %s\n", f.Fix)
+			}
+			if f.Severity == doctorFail {
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		if failed == 1 {
+			return fmt.Errorf("found 1 problem")
+		}
+		return fmt.Errorf("found %d problems", failed)
+	}
+
+	return nil
+}
+
+// checkEnvFiles checks that the env files of the shells that are set up
+// exist, are up to date and are sourced by their startup files, and that
+// the current shell sourced one. A shell set up by goup env instead, with
+// GOUP_HOME and goup's bin directory in PATH, needs no env files.
+func checkEnvFiles() []doctorFinding {
+	path := filepath.SplitList(os.Getenv("PATH"))
+	setUp := os.Getenv("GOUP_HOME") == GoupDir() && slices.Contains(path, GoupBinDir())
+
+	var (
+		findings []doctorFinding
+		sourced  bool
+	)
+	for i, s := range shellEnvs() {
+		envFile := GoupDir(s.EnvFile)
+		b, err := os.ReadFile(envFile)
+		if err != nil {
+			// Only the POSIX shells are always set up by goup init.
+			if i == 0 && !setUp {
+				findings = append(findings, doctorFinding{doctorFail, fmt.Sprintf("the env file %s is missing", envFile), "run `goup init`"})
+			}
+			continue
+		}
+
+		if content, err := s.envFileContent(); err == nil && content != string(b) {
+			findings = append(findings, doctorFinding{doctorWarn, fmt.Sprintf("the env file %s is out of date", envFile), "run `goup init`"})
+		}
+
+		if !s.sourcedByStartupFiles() {
+			if !setUp {
+				findings = append(findings, doctorFinding{doctorFail, fmt.Sprintf("%s is not sourced by any startup file of %s", envFile, s.Name), "run `goup init`"})
+			}
+			continue
+		}
+
+		sourced = true
+		findings = append(findings, doctorFinding{doctorOK, fmt.Sprintf("%s is sourced by the startup files of %s", envFile, s.Name), ""})
+	}
+
+	switch {
+	case setUp && !sourced:
+		findings = append(findings, doctorFinding{doctorOK, "the current shell is set up without the env files, e.g. by `goup env`", ""})
+	// The env files set GOUP_HOME, those of older versions of goup only
+	// add goup's bin directory to PATH.
+	case os.Getenv("GOUP_HOME") != GoupDir() && !slices.Contains(path, GoupBinDir()):
+		findings = append(findings, doctorFinding{doctorFail, "the current shell has not sourced the env file", fmt.Sprintf("open a new shell or run `%s`", ProfileFileSourceContent)})
+	}
+
+	return findings
+}
+
+// sourcedByStartupFiles reports whether a startup file of the shell sources
+// its env file.
+func (s shellEnv) sourcedByStartupFiles() bool {
+	for _, f := range s.StartupFiles {
+		if b, err := os.ReadFile(f); err == nil && slices.Contains(splitLines(string(b)), s.SourceLine) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPath checks that the first go in PATH is one of goup.
+func checkPath() []doctorFinding {
+	var gos []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		bin := filepath.Join(dir, goExe())
+		if fi, err := os.Stat(bin); err == nil && !fi.IsDir() && !slices.Contains(gos, bin) {
+			gos = append(gos, bin)
+		}
+	}
+
+	first := slices.IndexFunc(gos, func(bin string) bool {
+		return isGoupPathDir(filepath.Dir(bin))
+	})
+	switch {
+	case len(gos) == 0:
+		return []doctorFinding{{doctorFail, "there is no go in PATH", fmt.Sprintf("add %s to PATH by sourcing the env file, or install Go with `goup install`", GoupCurrentBinDir())}}
+	case first < 0:
+		return []doctorFinding{{doctorFail, fmt.Sprintf("the go of goup is not in PATH, %s is used", gos[0]), fmt.Sprintf("add %s to PATH by sourcing the env file", GoupCurrentBinDir())}}
+	case first > 0:
+		return []doctorFinding{{doctorFail, fmt.Sprintf("%s comes before the go of goup %s in PATH", strings.Join(gos[:first], ", "), gos[first]),
+			fmt.Sprintf("source the env file after the other Go setup in your shell startup files, or remove %s from PATH", filepath.Dir(gos[0]))}}
+	}
+
+	findings := []doctorFinding{{doctorOK, fmt.Sprintf("%s is the first go in PATH", gos[0]), ""}}
+	if len(gos) > 1 {
+		findings = append(findings, doctorFinding{doctorOK, fmt.Sprintf("other go commands in PATH are shadowed: %s", strings.Join(gos[1:], ", ")), ""})
+	}
+
+	return findings
+}
+
+// checkGorootEnv checks that GOROOT, if set, is the GOROOT of a Go of
+// goup.
+func checkGorootEnv() []doctorFinding {
+	goroot := os.Getenv("GOROOT")
+	if goroot == "" {
+		return []doctorFinding{{doctorOK, "GOROOT is not set", ""}}
+	}
+
+	if filepath.Clean(goroot) == GoupCurrentDir() || isGoupPathDir(filepath.Join(goroot, "bin")) {
+		return []doctorFinding{{doctorOK, fmt.Sprintf("GOROOT is set to %s of goup", goroot), ""}}
+	}
+
+	return []doctorFinding{{doctorFail, fmt.Sprintf("GOROOT is set to %s, which overrides the Go of goup", goroot),
+		"remove the line setting GOROOT from your shell startup files, the env file sets it up"}}
+}
+
+// checkCurrentLink checks that the current link points to an installed Go.
+func checkCurrentLink() []doctorFinding {
+	current := GoupCurrentDir()
+	target, err := os.Readlink(current)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []doctorFinding{{doctorWarn, "no default Go is set", "run `goup install` or `goup set`"}}
+		}
+		return []doctorFinding{{doctorFail, fmt.Sprintf("%s is not a link: %v", current, err), "run `goup set` to recreate it"}}
+	}
+
+	if !isGoroot(current) {
+		return []doctorFinding{{doctorFail, fmt.Sprintf("%s points to %s, which is not an installed Go", current, target), "run `goup set` with an installed version"}}
+	}
+
+	return []doctorFinding{{doctorOK, fmt.Sprintf("the default Go is %s", strings.TrimPrefix(filepath.Base(target), "go")), ""}}
+}
+
+// checkVersionDirs checks for version directories of installs that didn't
+// finish and for archives left in them. The archives of complete installs
+// are kept for goup verify --repair, only their total size is reported.
+func checkVersionDirs() []doctorFinding {
+	var (
+		findings []doctorFinding
+		kept     int
+		keptSize int64
+	)
+	for _, store := range versionStores() {
+		files, err := os.ReadDir(store.Dir)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			name := file.Name()
+			dir := filepath.Join(store.Dir, name)
+			if !file.IsDir() || !strings.HasPrefix(name, "go") {
+				continue
+			}
+
+			// goup bisect never installs the commits it builds.
+			if name == bisectVersion {
+				continue
+			}
+			// gotip built by older goup releases has no install
+			// metadata.
+			if name == tipVersion || checkInstalled(dir) {
+				for _, a := range versionArchives(dir) {
+					if fi, err := os.Stat(a); err == nil {
+						kept++
+						keptSize += fi.Size()
+					}
+				}
+				continue
+			}
+
+			// A tip build that failed is kept to be built again.
+			if isMutableVersion(name) {
+				findings = append(findings, doctorFinding{doctorWarn, fmt.Sprintf("the tip build in %s didn't finish", dir),
+					fmt.Sprintf("build it again with the `goup install tip` command that created it, or remove it with `goup remove %s`", strings.TrimPrefix(name, "go"))})
+				continue
+			}
+
+			fix := fmt.Sprintf("reinstall it with `goup remove %s` and `goup install %s`", strings.TrimPrefix(name, "go"), strings.TrimPrefix(name, "go"))
+			findings = append(findings, doctorFinding{doctorFail, fmt.Sprintf("%s has no install metadata %s, its install didn't finish", dir, installMetadataFile), fix})
+
+			for _, a := range versionArchives(dir) {
+				var size string
+				if fi, err := os.Stat(a); err == nil {
+					size = " (" + formatSize(fi.Size()) + ")"
+				}
+				findings = append(findings, doctorFinding{doctorWarn, fmt.Sprintf("the archive %s%s is left over", a, size), fix})
+			}
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{doctorOK, "all installed versions are complete", ""})
+	}
+	switch {
+	case kept == 1:
+		findings = append(findings, doctorFinding{doctorOK, fmt.Sprintf("1 archive of an installed version takes %s, it is kept for `goup verify --repair`", formatSize(keptSize)), ""})
+	case kept > 1:
+		findings = append(findings, doctorFinding{doctorOK, fmt.Sprintf("%d archives of installed versions take %s, they are kept for `goup verify --repair`", kept, formatSize(keptSize)), ""})
+	}
+
+	return findings
+}
+
+// versionArchives returns the archives in the version directory dir.
+func versionArchives(dir string) []string {
+	archives, _ := filepath.Glob(filepath.Join(dir, "*.tar.gz"))
+	zips, _ := filepath.Glob(filepath.Join(dir, "*.zip"))
+	return append(archives, zips...)
+}
+
+// checkGoHost checks that the Go host can be reached.
+func checkGoHost() []doctorFinding {
+	host := GetGoHost()
+	svc := service.NewGoReleaseService(host)
+	if err := svc.Ping(doctorPingTimeout); err != nil {
+		return []doctorFinding{{doctorFail, fmt.Sprintf("%s can't be reached: %v", host, err),
+			"check your network and proxy settings, or set GOUP_GO_HOST to a reachable mirror"}}
+	}
+
+	return []doctorFinding{{doctorOK, fmt.Sprintf("%s can be reached", host), ""}}
+}
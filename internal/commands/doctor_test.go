@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckVersionDirs(t *testing.T) {
+	home := setTestGoupHome(t)
+	t.Setenv("GOUP_SHARED_STORE", filepath.Join(home, "shared"))
+
+	files := map[string]int{
+		"go1.22.0/" + unpackedOkay:                 0,
+		"go1.22.0/go1.22.0.linux-amd64.tar.gz":     1 << 20,
+		"go1.21.0/" + installMetadataFile:          0,
+		"go1.21.0/go1.21.0.linux-amd64.tar.gz":     1 << 20,
+		"go1.20.0/go1.20.0.linux-amd64.tar.gz":     10,
+		"shared/go1.19.0/go1.19.0.linux-amd64.zip": 10,
+		bisectVersion + "/VERSION":                 10,
+		"gotip-master/.git":                        10,
+	}
+	for f, size := range files {
+		file := filepath.Join(home, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, f := range checkVersionDirs() {
+		got = append(got, f.Severity+" "+f.Message)
+	}
+	want := []string{
+		"fail " + filepath.Join(home, "go1.20.0") + " has no install metadata " + installMetadataFile + ", its install didn't finish",
+		"warn the archive " + filepath.Join(home, "go1.20.0", "go1.20.0.linux-amd64.tar.gz") + " (10 B) is left over",
+		"warn the tip build in " + filepath.Join(home, "gotip-master") + " didn't finish",
+		"fail " + filepath.Join(home, "shared", "go1.19.0") + " has no install metadata " + installMetadataFile + ", its install didn't finish",
+		"warn the archive " + filepath.Join(home, "shared", "go1.19.0", "go1.19.0.linux-amd64.zip") + " (10 B) is left over",
+		"ok 2 archives of installed versions take 2.0 MiB, they are kept for `goup verify --repair`",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checkVersionDirs() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckEnvFilesGoupEnv(t *testing.T) {
+	home := setTestGoupHome(t)
+	t.Setenv("GOUP_HOME", home)
+	t.Setenv("PATH", GoupBinDir()+string(os.PathListSeparator)+GoupCurrentBinDir()+string(os.PathListSeparator)+"/usr/bin")
+
+	// Set up by eval "$(goup env)" without goup init.
+	var got []string
+	for _, f := range checkEnvFiles() {
+		got = append(got, f.Severity+" "+f.Message)
+	}
+	want := []string{"ok the current shell is set up without the env files, e.g. by `goup env`"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checkEnvFiles() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	t.Setenv("GOUP_HOME", "")
+	t.Setenv("PATH", "/usr/bin")
+	var failed int
+	for _, f := range checkEnvFiles() {
+		if f.Severity == doctorFail {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Errorf("checkEnvFiles() without any setup has %d failures, want 2", failed)
+	}
+}
@@ -0,0 +1,248 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	envCmdShellFlag string
+	envCmdJSONFlag  bool
+)
+
+func envCmd() *cobra.Command {
+	envCmd := &cobra.Command{
+		Use:   "env [VERSION]",
+		Short: "Print the shell commands to set up the Go environment",
+		Long: `Print the shell commands that set PATH, GOROOT and GOUP_HOME up for goup and a
+Go version, to be evaluated in a shell startup file instead of sourcing the env
+file. If no version is provided, the environment follows the default Go set by
+goup set.`,
+		Example: `
+  eval "$(goup env --shell zsh)"
+  goup env --shell fish | source
+  goup env --shell pwsh 1.15.2 | Out-String | Invoke-Expression
+  goup env --json
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runEnv,
+	}
+
+	envCmd.PersistentFlags().StringVar(&envCmdShellFlag, "shell", "", "shell to print the commands for: sh, bash, zsh, fish, nu, pwsh or elvish, defaults to the shell in $SHELL")
+	envCmd.PersistentFlags().BoolVar(&envCmdJSONFlag, "json", false, "Print the environment as JSON")
+
+	return envCmd
+}
+
+// goEnv is the environment of goup and a Go version.
+type goEnv struct {
+	Version string `json:"version,omitempty"`
+	GOROOT  string `json:"goroot,omitempty"`
+	// Path are the directories that are prepended to PATH.
+	Path []string `json:"path"`
+	// Env are the variables to set. PATH is the new value of PATH,
+	// without the directories of other Go versions installed by goup.
+	Env map[string]string `json:"env"`
+}
+
+func runEnv(cmd *cobra.Command, args []string) error {
+	var ver string
+	if len(args) > 0 {
+		ver = args[0]
+	}
+
+	env, err := newGoEnv(ver)
+	if err != nil {
+		return err
+	}
+
+	if envCmdJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(env)
+	}
+
+	shell := envCmdShellFlag
+	if shell == "" {
+		shell = defaultShell()
+	}
+	name, ok := shellFamily(shell)
+	if !ok {
+		return fmt.Errorf("unsupported shell %q, must be one of sh, bash, zsh, fish, nu, pwsh or elvish", shell)
+	}
+
+	fmt.Print(env.format(name))
+	return nil
+}
+
+// newGoEnv returns the environment of the Go version ver, or of the
+// default Go if ver is empty. The default Go is looked up through the
+// current link so that the environment follows goup set.
+func newGoEnv(ver string) (*goEnv, error) {
+	env := &goEnv{}
+	if ver == "" {
+		env.Path = []string{GoupBinDir(), GoupCurrentBinDir()}
+		if cur, err := currentGoVersion(); err == nil {
+			env.Version = strings.TrimPrefix(cur, "go")
+			env.GOROOT = GoupCurrentDir()
+		}
+	} else {
+		ver = versionName(ver)
+		goroot := goupVersionDir(ver)
+		if !isGoroot(goroot) {
+			return nil, fmt.Errorf("Go version %s is not installed. Install it with `goup install`.", strings.TrimPrefix(ver, "go"))
+		}
+
+		env.Version = strings.TrimPrefix(ver, "go")
+		env.GOROOT = goroot
+		env.Path = []string{GoupBinDir(), filepath.Join(goroot, "bin")}
+	}
+
+	path := env.Path
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if !isGoupPathDir(dir) {
+			path = append(path, dir)
+		}
+	}
+	env.Env = map[string]string{
+		"GOUP_HOME": GoupDir(),
+		"PATH":      strings.Join(path, string(os.PathListSeparator)),
+	}
+	if env.GOROOT != "" {
+		env.Env["GOROOT"] = env.GOROOT
+	}
+
+	return env, nil
+}
+
+// isGoupPathDir reports whether dir is goup's bin directory or the bin
+// directory of a Go in GoupDir or the shared store, which are replaced when
+// setting PATH.
+func isGoupPathDir(dir string) bool {
+	dir = filepath.Clean(dir)
+	if dir == GoupBinDir() {
+		return true
+	}
+
+	for _, s := range versionStores() {
+		rel, err := filepath.Rel(s.Dir, dir)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(rel, string(filepath.Separator))
+		if len(parts) == 2 && parts[0] != ".." && parts[1] == "bin" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// format returns the commands that set the environment up in the shell
+// family name, see shellFamily.
+func (e *goEnv) format(name string) string {
+	var b strings.Builder
+	b.WriteString(setEnvCommand(name, "GOUP_HOME", e.Env["GOUP_HOME"]))
+	if e.GOROOT != "" {
+		b.WriteString(setEnvCommand(name, "GOROOT", e.GOROOT))
+	}
+
+	path := filepath.SplitList(e.Env["PATH"])
+
+	switch name {
+	case "fish":
+		b.WriteString("set -gx PATH")
+		for _, dir := range path {
+			b.WriteString(" " + fishQuote(dir))
+		}
+		b.WriteString("\n")
+	case "nu":
+		quoted := make([]string, len(path))
+		for i, dir := range path {
+			quoted[i] = strconv.Quote(dir)
+		}
+		fmt.Fprintf(&b, "$env.PATH = [%s]\n", strings.Join(quoted, " "))
+	case "pwsh":
+		fmt.Fprintf(&b, "$env:PATH = %s\n", pwshQuote(e.Env["PATH"]))
+	case "elvish":
+		quoted := make([]string, len(path))
+		for i, dir := range path {
+			quoted[i] = pwshQuote(dir)
+		}
+		fmt.Fprintf(&b, "set paths = [%s]\n", strings.Join(quoted, " "))
+	default:
+		fmt.Fprintf(&b, "export PATH=%s\n", shQuote(e.Env["PATH"]))
+	}
+
+	return b.String()
+}
+
+// setEnvCommand returns the command that exports the variable key in the
+// shell family name.
+func setEnvCommand(name, key, value string) string {
+	switch name {
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s\n", key, fishQuote(value))
+	case "nu":
+		return fmt.Sprintf("$env.%s = %s\n", key, strconv.Quote(value))
+	case "pwsh":
+		return fmt.Sprintf("$env:%s = %s\n", key, pwshQuote(value))
+	case "elvish":
+		return fmt.Sprintf("set-env %s %s\n", key, pwshQuote(value))
+	default:
+		return fmt.Sprintf("export %s=%s\n", key, shQuote(value))
+	}
+}
+
+// unsetEnvCommand returns the command that removes the variable key in the
+// shell family name.
+func unsetEnvCommand(name, key string) string {
+	switch name {
+	case "fish":
+		return fmt.Sprintf("set -e %s\n", key)
+	case "nu":
+		return fmt.Sprintf("hide-env -i %s\n", key)
+	case "pwsh":
+		return fmt.Sprintf("Remove-Item -ErrorAction SilentlyContinue Env:%s\n", key)
+	case "elvish":
+		return fmt.Sprintf("unset-env %s\n", key)
+	default:
+		return fmt.Sprintf("unset %s\n", key)
+	}
+}
+
+// shQuote quotes s for POSIX shells.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, where backslashes and single quotes are
+// escaped in single-quoted strings.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// pwshQuote quotes s for PowerShell and elvish, where single quotes are
+// doubled in single-quoted strings.
+func pwshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// defaultShell returns the shell of the user, falling back to sh.
+func defaultShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+	if runtime.GOOS == "windows" {
+		return "pwsh"
+	}
+
+	return "sh"
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		sh   string
+		fish string
+		pwsh string
+	}{
+		{"/home/u/.go", `'/home/u/.go'`, `'/home/u/.go'`, `'/home/u/.go'`},
+		{"", `''`, `''`, `''`},
+		{"it's", `'it'\''s'`, `'it\'s'`, `'it''s'`},
+		{`C:\Go\bin`, `'C:\Go\bin'`, `'C:\\Go\\bin'`, `'C:\Go\bin'`},
+		{"$HOME `x` \"y\"", "'$HOME `x` \"y\"'", "'$HOME `x` \"y\"'", "'$HOME `x` \"y\"'"},
+	} {
+		if got := shQuote(tt.in); got != tt.sh {
+			t.Errorf("shQuote(%q) = %s, want %s", tt.in, got, tt.sh)
+		}
+		if got := fishQuote(tt.in); got != tt.fish {
+			t.Errorf("fishQuote(%q) = %s, want %s", tt.in, got, tt.fish)
+		}
+		if got := pwshQuote(tt.in); got != tt.pwsh {
+			t.Errorf("pwshQuote(%q) = %s, want %s", tt.in, got, tt.pwsh)
+		}
+	}
+}
+
+func TestIsGoupPathDir(t *testing.T) {
+	home := setTestGoupHome(t)
+	shared := setTestSharedStore(t)
+
+	for _, tt := range []struct {
+		dir  string
+		want bool
+	}{
+		{GoupBinDir(), true},
+		{filepath.Join(home, "bin") + string(filepath.Separator), true},
+		{filepath.Join(home, "current", "bin"), true},
+		{filepath.Join(home, "go1.22.0", "bin"), true},
+		{filepath.Join(shared, "go1.22.0", "bin"), true},
+		{filepath.Join(home, "go1.22.0", "pkg", "bin"), false},
+		{filepath.Join(home, "go1.22.0"), false},
+		{home, false},
+		{filepath.Join(filepath.Dir(home), "go1.22.0", "bin"), false},
+		{"/usr/local/go/bin", false},
+	} {
+		if got := isGoupPathDir(tt.dir); got != tt.want {
+			t.Errorf("isGoupPathDir(%s) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
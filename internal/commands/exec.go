@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func execCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "exec [VERSION] -- <COMMAND> [ARGS]...",
+		Short: "Run a command with a Go version",
+		Long: `Run a command with GOROOT and PATH set up for a Go version. If no version is
+provided, the default Go is used. The command must follow --, so that its flags
+are not taken for flags of goup. goup exits with the exit code of the command.`,
+		Example: `
+  goup exec 1.15.2 -- go version
+  goup exec mygo -- go test ./...
+  goup exec -- go env GOROOT
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runExec,
+	}
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	var ver string
+	var command []string
+	switch dash := cmd.ArgsLenAtDash(); dash {
+	case -1:
+		return errors.New("the command must follow --, e.g. goup exec 1.22.0 -- go version")
+	case 0:
+		command = args
+	case 1:
+		ver, command = args[0], args[1:]
+	default:
+		return errors.New("only one version can be provided")
+	}
+	if len(command) == 0 {
+		return errors.New("no command is specified")
+	}
+
+	if ver == "" {
+		var err error
+		ver, err = currentGoVersion()
+		if err != nil {
+			return fmt.Errorf("no default Go is set: %v", err)
+		}
+	}
+	ver = versionName(ver)
+
+	goroot := goupVersionDir(ver)
+	if !isGoroot(goroot) {
+		return fmt.Errorf("Go version %s is not installed. Install it with `goup install`.", strings.TrimPrefix(ver, "go"))
+	}
+
+	// Set up the environment of goup itself so that the command is
+	// looked up in the GOROOT first.
+	if err := os.Setenv("GOROOT", goroot); err != nil {
+		return err
+	}
+	if err := os.Setenv("PATH", filepath.Join(goroot, "bin")+string(os.PathListSeparator)+os.Getenv("PATH")); err != nil {
+		return err
+	}
+
+	c := exec.Command(command[0], command[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The command has reported its error itself.
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return &ExitError{Code: exitErr.ExitCode()}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ExitError is returned by commands that exit with Code, without an error
+// message of goup.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/owenthereal/goup/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func homeCmd() *cobra.Command {
+	homeCmd := &cobra.Command{
+		Use:   "home",
+		Short: "Print or move the goup home",
+		Long: `Print the directory goup and Go are installed in. It is GOUP_HOME if set,
+$HOME/.go if goup is set up there, then $XDG_DATA_HOME/goup if XDG_DATA_HOME
+is set, and $HOME/.go otherwise.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(GoupDir())
+		},
+	}
+
+	homeCmd.AddCommand(homeMigrateCmd())
+
+	return homeCmd
+}
+
+func homeMigrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate <NEWDIR>",
+		Short: "Move the goup home to another directory",
+		Long: `Move goup and the installed Go versions to another directory, e.g. to free
+$HOME/.go for GOPATH or to use a bigger disk. The current link, the tip
+worktrees and the env files are updated, and so are the lines sourcing the env
+files in the shell startup files. The env files set GOUP_HOME to the new
+directory.`,
+		Example: `
+  goup home migrate ~/.local/share/goup
+  goup home migrate /mnt/data/goup
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: runHomeMigrate,
+	}
+}
+
+func runHomeMigrate(cmd *cobra.Command, args []string) error {
+	oldHome := GoupDir()
+	newHome, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+
+	if isSubdir(oldHome, newHome) || isSubdir(newHome, oldHome) {
+		return fmt.Errorf("%s and %s must not contain each other", oldHome, newHome)
+	}
+	if files, err := os.ReadDir(newHome); err == nil && len(files) > 0 {
+		return fmt.Errorf("%s is not empty", newHome)
+	}
+
+	// The source lines depend on the home, replace the old ones later.
+	oldEnvs := shellEnvs()
+
+	cmd.SilenceUsage = true
+	logger.Printf("Moving %s to %s ...", oldHome, newHome)
+	if err := moveDir(oldHome, newHome); err != nil {
+		return fmt.Errorf("failed to move %s: %v", oldHome, err)
+	}
+	goupHome = newHome
+
+	if err := setUpMovedHome(oldHome, newHome); err != nil {
+		return rollbackHomeMove(oldHome, newHome, err)
+	}
+
+	if dirs, err := listTipWorktrees(); err == nil && len(dirs) > 0 && git.Installed() {
+		repo := tipRepo{dir: goupVersionDir(tipVersion)}
+		if err := repo.git(append([]string{"worktree", "repair"}, dirs...)...); err != nil {
+			logger.Warnf("failed to repair the git worktrees of tip: %v", err)
+		}
+	}
+
+	var (
+		edits       []fileEdit
+		sourceLines []string
+	)
+	for i, s := range shellEnvs() {
+		if _, err := os.Stat(GoupDir(s.EnvFile)); err != nil {
+			// The shell is not set up.
+			continue
+		}
+		for _, f := range s.StartupFiles {
+			e, err := replaceLineEdit(f, oldEnvs[i].SourceLine, s.SourceLine)
+			if err != nil {
+				return fmt.Errorf("moved the goup home to %s, but failed to read %s: %v; run `goup init` to update the shell startup files", newHome, f, err)
+			}
+			if e.Exists {
+				edits = append(edits, e)
+			}
+		}
+		sourceLines = append(sourceLines, fmt.Sprintf("  %-8s%s", s.Name, s.SourceLine))
+	}
+	// The home is moved already, so the startup files are not rolled back.
+	if err := applyEdits(edits); err != nil {
+		return fmt.Errorf("moved the goup home to %s, but failed to update the shell startup files: %v; run `goup init` to update them", newHome, err)
+	}
+
+	logger.Printf("Moved the goup home to %s", newHome)
+	if len(sourceLines) > 0 {
+		fmt.Printf("\nTo configure your current shell run:\n\n%s\n", strings.Join(sourceLines, "\n"))
+	}
+
+	return nil
+}
+
+// setUpMovedHome points the links in the goup home moved from oldHome to
+// newHome and its env files to newHome.
+func setUpMovedHome(oldHome, newHome string) error {
+	if err := relinkHome(oldHome, newHome); err != nil {
+		return err
+	}
+
+	return rewriteEnvFiles()
+}
+
+// rollbackHomeMove moves the goup home back from newHome to oldHome after
+// setting it up in newHome failed with err.
+func rollbackHomeMove(oldHome, newHome string, err error) error {
+	logger.Warnf("failed to set up the goup home in %s, moving it back to %s: %v", newHome, oldHome, err)
+
+	goupHome = oldHome
+	if rerr := moveDir(newHome, oldHome); rerr != nil {
+		return fmt.Errorf("failed to set up the goup home in %s: %v; moving it back to %s failed too: %v; move it back by hand and run `goup init`", newHome, err, oldHome, rerr)
+	}
+	if rerr := setUpMovedHome(newHome, oldHome); rerr != nil {
+		return fmt.Errorf("failed to set up the goup home in %s: %v; it is moved back to %s, but setting it up again failed: %v; run `goup set` and `goup init`", newHome, err, oldHome, rerr)
+	}
+
+	return fmt.Errorf("failed to set up the goup home in %s, it is moved back to %s: %v", newHome, oldHome, err)
+}
+
+// rewriteEnvFiles writes the env files of the shells that are set up for
+// the current goup home.
+func rewriteEnvFiles() error {
+	for _, s := range shellEnvs() {
+		if _, err := os.Stat(GoupDir(s.EnvFile)); err != nil {
+			// The shell is not set up.
+			continue
+		}
+		if err := s.writeEnvFile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isSubdir reports whether dir is parent or below it.
+func isSubdir(parent, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// moveDir moves the directory src to dst, copying it if it can't be
+// renamed, e.g. to another file system.
+func moveDir(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	// An empty dst is replaced.
+	os.Remove(dst)
+
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	logger.Debugf("failed to rename %s, copying it: %v", src, err)
+
+	if err := copyDir(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+
+	return os.RemoveAll(src)
+}
+
+// copyDir copies the directory src to dst, keeping symlinks.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			// Keep directories writable to copy their files.
+			return os.MkdirAll(target, fi.Mode().Perm()|0700)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// relinkHome points the symlinks in newHome that point into oldHome, such
+// as the current link, to newHome.
+func relinkHome(oldHome, newHome string) error {
+	files, err := os.ReadDir(newHome)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		link := filepath.Join(newHome, f.Name())
+		target, err := os.Readlink(link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(oldHome, target)
+		if err != nil || !filepath.IsAbs(target) || !filepath.IsLocal(rel) {
+			continue
+		}
+
+		os.Remove(link)
+		if err := os.Symlink(filepath.Join(newHome, rel), link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceLineEdit returns the edit replacing the lines old in file with
+// new. The file is backed up before it is changed.
+func replaceLineEdit(file, old, new string) (fileEdit, error) {
+	e, err := newFileEdit(file, true)
+	if err != nil || !e.Exists {
+		return e, err
+	}
+
+	lines := splitLines(e.Old)
+	found := false
+	for i, l := range lines {
+		if l == old {
+			lines[i] = new
+			found = true
+		}
+	}
+	if found {
+		e.New = joinLines(lines)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHomeExpr(t *testing.T) {
+	oldHomedir, oldGoupHome := homedir, goupHome
+	t.Cleanup(func() { homedir, goupHome = oldHomedir, oldGoupHome })
+	homedir = "/home/u"
+
+	for _, tt := range []struct {
+		home  string
+		shell string
+		want  string
+	}{
+		{"/home/u/.go", "sh", `"$HOME/.go/env"`},
+		{"/home/u/.go", "fish", `"$HOME/.go/env"`},
+		{"/home/u/.go", "nu", `($nu.home-path | path join ".go/env")`},
+		{"/home/u/.go", "pwsh", `([IO.Path]::Combine($HOME, '.go', 'env'))`},
+		{"/home/u/.go", "elvish", `$E:HOME'/.go/env'`},
+		{"/home/u/.local/share/goup", "sh", `"$HOME/.local/share/goup/env"`},
+		{"/home/u/.local/share/goup", "pwsh", `([IO.Path]::Combine($HOME, '.local', 'share', 'goup', 'env'))`},
+		{"/opt/it's goup", "sh", `'/opt/it'\''s goup/env'`},
+		{"/opt/it's goup", "fish", `'/opt/it'\''s goup/env'`},
+		{"/opt/it's goup", "nu", `"/opt/it's goup/env"`},
+		{"/opt/it's goup", "pwsh", `'/opt/it''s goup/env'`},
+		{"/opt/it's goup", "elvish", `'/opt/it''s goup/env'`},
+		// A sibling of the home directory is not below it.
+		{"/home/u2/.go", "sh", `'/home/u2/.go/env'`},
+	} {
+		goupHome = tt.home
+		if got := homeExpr(tt.shell, "env"); got != tt.want {
+			t.Errorf("homeExpr(%q, \"env\") with the goup home %s = %s, want %s", tt.shell, tt.home, got, tt.want)
+		}
+	}
+}
+
+func TestRelinkHome(t *testing.T) {
+	oldHome := filepath.Join(t.TempDir(), "old")
+	newHome := t.TempDir()
+	other := t.TempDir()
+
+	for _, dir := range []string{"go1.22.0", "go1.21.0"} {
+		if err := os.MkdirAll(filepath.Join(newHome, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		// The current link points into the old home.
+		"current": filepath.Join(oldHome, "go1.22.0"),
+		// Linked versions point outside of it.
+		"go1.20.0": other,
+		// Relative links move with the home.
+		"go1.21": "go1.21.0",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(newHome, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := relinkHome(oldHome, newHome); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"current":  filepath.Join(newHome, "go1.22.0"),
+		"go1.20.0": other,
+		"go1.21":   "go1.21.0",
+	} {
+		got, err := os.Readlink(filepath.Join(newHome, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s points to %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestReplaceLineEdit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".profile")
+	content := "export A=1\n" + profileBeginMarker + "\nsource \"/old/env\"\n" + profileEndMarker + "\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := replaceLineEdit(file, `source "/old/env"`, `source "/new/env"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Backup {
+		t.Error("the edit doesn't back up the file")
+	}
+	want := "export A=1\n" + profileBeginMarker + "\nsource \"/new/env\"\n" + profileEndMarker + "\n"
+	if e.New != want {
+		t.Errorf("New = %q, want %q", e.New, want)
+	}
+
+	e, err = replaceLineEdit(file, `source "/other/env"`, `source "/new/env"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.changed() {
+		t.Errorf("the edit changes %s without the old line", file)
+	}
+
+	e, err = replaceLineEdit(filepath.Join(t.TempDir(), ".bashrc"), `source "/old/env"`, `source "/new/env"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Exists {
+		t.Error("a missing file exists")
+	}
+}
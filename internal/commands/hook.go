@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// hookVersionEnv is the shell variable the hook records the version it set
+// up in, so that the environment is only changed when the version changes.
+const hookVersionEnv = "GOUP_HOOK_VERSION"
+
+// hookDefault is the hook version of the default Go, set outside of
+// projects.
+const hookDefault = "default"
+
+// hookMissingSuffix marks a hook version that is not installed, so that it
+// is reported and installed only once.
+const hookMissingSuffix = "!missing"
+
+var (
+	hookCmdAutoInstallFlag bool
+	hookCmdExportFlag      bool
+)
+
+var hookScripts = map[string]string{
+	"bash": `_goup_hook() {
+  local previous_exit_status=$?
+  eval "$({{ .Command }})"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_goup_hook;"* ]]; then
+  if [[ "$(declare -p PROMPT_COMMAND 2>&1)" == "declare -a"* ]]; then
+    PROMPT_COMMAND=(_goup_hook "${PROMPT_COMMAND[@]}")
+  else
+    PROMPT_COMMAND="_goup_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+  fi
+fi
+`,
+	"zsh": `_goup_hook() {
+  eval "$({{ .Command }})"
+}
+typeset -ag precmd_functions chpwd_functions
+if (( ! ${precmd_functions[(I)_goup_hook]} )); then
+  precmd_functions=(_goup_hook $precmd_functions)
+fi
+if (( ! ${chpwd_functions[(I)_goup_hook]} )); then
+  chpwd_functions=(_goup_hook $chpwd_functions)
+fi
+`,
+	"fish": `function _goup_hook --on-variable PWD --on-event fish_prompt
+    {{ .Command }} | source
+end
+`,
+}
+
+func hookCmd() *cobra.Command {
+	hookCmd := &cobra.Command{
+		Use:   "hook <bash|zsh|fish>",
+		Short: "Print a shell hook that switches Go per project",
+		Long: `Print a shell hook that switches the Go of the shell session when entering a
+directory with a .go-version file or a go.mod file with a toolchain directive.
+PATH and GOROOT are set up for the version of the nearest one, and for the
+default Go outside of projects. The default Go itself is not changed.`,
+		Example: `
+  eval "$(goup hook bash)" # in ~/.bashrc
+  eval "$(goup hook zsh --auto-install)" # in ~/.zshrc
+  goup hook fish | source # in ~/.config/fish/config.fish
+`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE:      runHook,
+	}
+
+	hookCmd.PersistentFlags().BoolVar(&hookCmdAutoInstallFlag, "auto-install", false, "install the version of a project if it is not installed")
+	hookCmd.PersistentFlags().BoolVar(&hookCmdExportFlag, "export", false, "print the commands that set up the environment of the current directory, run by the hook")
+	hookCmd.PersistentFlags().MarkHidden("export")
+
+	return hookCmd
+}
+
+func runHook(cmd *cobra.Command, args []string) error {
+	shell := args[0]
+	script, ok := hookScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q, must be one of bash, zsh or fish", shell)
+	}
+
+	if hookCmdExportFlag {
+		out, err := hookExport(shell)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := []string{shQuote(exe), "hook", shell, "--export"}
+	if hookCmdAutoInstallFlag {
+		command = append(command, "--auto-install")
+	}
+
+	tmpl, err := template.New("").Parse(script)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(os.Stdout, struct{ Command string }{strings.Join(command, " ")})
+}
+
+// hookExport returns the commands that set up the environment of the
+// project in the working directory, or of the default Go outside of
+// projects. Nothing is returned if the version is already set up. Notices
+// are printed to stderr, stdout is evaluated by the shell.
+func hookExport(shell string) (string, error) {
+	name, _ := shellFamily(shell)
+	previous := os.Getenv(hookVersionEnv)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	pv, ok, err := findProjectVersion(wd)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		if previous == hookDefault || previous == "" {
+			// The shell starts with the default Go from its env file.
+			return "", nil
+		}
+
+		env, err := newGoEnv("")
+		if err != nil {
+			return "", err
+		}
+		if env.Version != "" {
+			fmt.Fprintf(os.Stderr, "goup: using the default Go %s\n", env.Version)
+		}
+
+		out := env.format(name)
+		if env.GOROOT == "" {
+			out += unsetEnvCommand(name, "GOROOT")
+		}
+		return out + setEnvCommand(name, hookVersionEnv, hookDefault), nil
+	}
+
+	ver, err := pv.versionName()
+	if err != nil {
+		return "", err
+	}
+	if previous == ver || previous == ver+hookMissingSuffix {
+		return "", nil
+	}
+
+	if !isGoroot(goupVersionDir(ver)) && hookCmdAutoInstallFlag {
+		fmt.Fprintf(os.Stderr, "goup: installing Go %s for %s\n", strings.TrimPrefix(ver, "go"), pv.File)
+		if err := hookInstall(pv.Version); err != nil {
+			fmt.Fprintf(os.Stderr, "goup: failed to install Go %s: %v\n", strings.TrimPrefix(ver, "go"), err)
+		}
+	}
+
+	if !isGoroot(goupVersionDir(ver)) {
+		fmt.Fprintf(os.Stderr, "goup: Go %s for %s is not installed, install it with `goup install --no-set %s`\n",
+			strings.TrimPrefix(ver, "go"), pv.File, strings.TrimPrefix(ver, "go"))
+		return setEnvCommand(name, hookVersionEnv, ver+hookMissingSuffix), nil
+	}
+
+	env, err := newGoEnv(ver)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "goup: using Go %s from %s\n", env.Version, pv.File)
+
+	return env.format(name) + setEnvCommand(name, hookVersionEnv, ver), nil
+}
+
+// hookInstall installs ver without setting it as the default Go. It runs
+// goup itself so that its output goes to stderr, which the shell doesn't
+// evaluate.
+func hookInstall(ver string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, "install", "--yes", "--no-set", ver)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,9 +11,6 @@ import (
 )
 
 const (
-	GoupEnvFileContent       = `export PATH="$HOME/.go/bin:$HOME/.go/current/bin:$PATH"`
-	ProfileFileSourceContent = `source "$HOME/.go/env"`
-
 	welcomeTmpl = `Welcome to Goup!
 
 Goup and Go will be located at:
@@ -33,18 +29,22 @@ To get started you need Goup's bin directory ({{ .GoupBinDir }}) and
 Go's bin directory ({{ .CurrentGoBinDir }}) in your PATH environment
 variable. These two paths will be added to your PATH environment variable by
 modifying the profile files located at:
-{{ range $index, $elem :=.ProfileFiles }}
-  {{ $elem -}}
-{{ end }}
+{{ range .Shells }}{{ range .StartupFiles }}
+  {{ . -}}
+{{ end }}{{ end }}
 
 Next time you log in this will be done automatically. To configure your
-current shell run source $HOME/.go/env.
+current shell run:
+{{ range .Shells }}
+  {{ printf "%-8s" .Name }}{{ .SourceLine -}}
+{{ end }}
 `
 )
 
 var (
 	initCmdSkipInstallFlag bool
 	initCmdSkipPromptFlag  bool
+	initCmdDryRunFlag      bool
 )
 
 func initCmd() *cobra.Command {
@@ -57,6 +57,7 @@ func initCmd() *cobra.Command {
 
 	initCmd.PersistentFlags().BoolVar(&initCmdSkipInstallFlag, "skip-install", false, "Skip installing Go")
 	initCmd.PersistentFlags().BoolVar(&initCmdSkipPromptFlag, "skip-prompt", false, "Skip confirmation prompt")
+	initCmd.PersistentFlags().BoolVar(&initCmdDryRunFlag, "dry-run", false, "Print the changes to the env and shell startup files as a diff without making them")
 
 	return initCmd
 }
@@ -71,13 +72,28 @@ func runInit(cmd *cobra.Command, args []string) error {
 		GoupDir         string
 		GoupBinDir      string
 		CurrentGoBinDir string
-		ProfileFiles    []string
+		Shells          []shellEnv
 	}{
 		GoupDir:         GoupDir(),
 		GoupBinDir:      GoupBinDir(),
 		CurrentGoBinDir: GoupCurrentBinDir(),
-		ProfileFiles:    ProfileFiles,
+		Shells:          detectShellEnvs(),
+	}
+
+	var edits []fileEdit
+	for _, s := range params.Shells {
+		e, err := s.edits()
+		if err != nil {
+			return err
+		}
+		edits = append(edits, e...)
 	}
+
+	if initCmdDryRunFlag {
+		printEdits(edits)
+		return nil
+	}
+
 	if err := tmpl.Execute(os.Stdout, params); err != nil {
 		return err
 	}
@@ -97,19 +113,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	}
 
-	ef := GoupEnvFile()
-	if err := os.MkdirAll(filepath.Dir(ef), 0755); err != nil {
-		return err
-	}
-
-	// ignore error, similar to rm -f
-	os.Remove(ef)
-
-	if err := os.WriteFile(ef, []byte(GoupEnvFileContent), 0664); err != nil {
-		return err
-	}
-
-	if err := appendSourceToProfiles(ProfileFiles); err != nil {
+	if err := applyEdits(edits); err != nil {
 		return err
 	}
 
@@ -125,66 +129,15 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func appendSourceToProfiles(profiles []string) error {
-	for _, profile := range profiles {
-		if err := appendToFile(profile, ProfileFileSourceContent); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func appendToFile(filename, value string) error {
-	ok, err := checkStringExistsFile(filename, value)
-	if err != nil {
-		return err
-	}
-	if ok {
-		return nil
-	}
-
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	if _, err := f.WriteString("\n" + value + "\n"); err != nil {
-		return err
-	}
-
-	return err
-}
-
-func checkStringExistsFile(filename, value string) (bool, error) {
-	file, err := os.OpenFile(filename, os.O_RDONLY, 0600)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		return false, err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == value {
-			return true, nil
-		}
-	}
-
-	return false, scanner.Err()
-}
-
 func checkInstalled(targetDir string) bool {
-	if _, err := os.Stat(filepath.Join(targetDir, unpackedOkay)); err == nil {
-		return true
+	for _, f := range []string{installMetadataFile, unpackedOkay} {
+		if _, err := os.Stat(filepath.Join(targetDir, f)); err == nil {
+			return true
+		}
 	}
 	return false
 }
 
-func setInstalled(targetDir string) error {
-	return os.WriteFile(filepath.Join(targetDir, unpackedOkay), nil, 0644)
+func setInstalled(targetDir string, md installMetadata) error {
+	return writeInstallMetadata(targetDir, md)
 }
@@ -12,15 +12,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
-	"strconv"
 	"strings"
 
 	"github.com/owenthereal/goup/internal/entity"
 	"github.com/owenthereal/goup/internal/service"
 
-	"github.com/manifoldco/promptui"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
@@ -32,6 +30,19 @@ const (
 
 var (
 	installCmdGoHostFlag string
+	installCmdOSFlag     string
+	installCmdArchFlag   string
+	installCmdSourceFlag bool
+	installCmdYesFlag    bool
+	installCmdCleanFlag  string
+	installCmdForceFlag  bool
+	installCmdPatchFlag  []string
+
+	installCmdVariantFlag string
+	installCmdEnvFlag     []string
+	installCmdTestFlag    string
+	installCmdNoSetFlag   bool
+	installCmdSharedFlag  bool
 )
 
 func GetGoSourceGitURL() string {
@@ -42,6 +53,14 @@ func GetGoSourceGitURL() string {
 	return gsURL
 }
 
+func GetGoSourceUpstreamGitURL() string {
+	gsuURL := os.Getenv("GOUP_GO_SOURCE_GIT_URL")
+	if gsuURL == "" {
+		gsuURL = goSourceUpsteamGitURL
+	}
+	return gsuURL
+}
+
 func GetGoHost() string {
 	gh := os.Getenv("GOUP_GO_HOST")
 	if gh == "" {
@@ -50,60 +69,146 @@ func GetGoHost() string {
 	return gh
 }
 
-func getGoArch() string {
-	if arch := os.Getenv("GOUP_GO_ARCH"); arch != "" {
-		return arch
-	}
-	return runtime.GOARCH
-}
-
-// getOS returns runtime.GOOS. It exists as a function just for lazy
-// testing of the Windows zip path when running on Linux/Darwin.
-func getOS() string {
-	return runtime.GOOS
-}
-
 func installCmd() *cobra.Command {
 	installCmd := &cobra.Command{
 		Use:   "install [VERSION]",
 		Short: `Install Go with a version`,
 		Long: `Install Go by providing a version. If no version is provided, install
 the latest Go. If the version is 'tip', an optional change list (CL)
-number can be provided.`,
+number can be provided. A branch, tag or commit of the Go repository can be
+built with 'tip@REF'.`,
 		Example: `
   goup install
   goup install 1.15.2
   goup install go1.15.2
   goup install tip # Compile Go tip
   goup install tip 1234 # 1234 is the CL number
+  goup install tip@release-branch.go1.22 # Compile a branch, tag or commit as tip-release-branch.go1.22
+  goup install --yes --clean=force tip 1234 # Compile a CL without prompting, e.g. in CI
+  goup install tip --patch ./fix.diff # Compile tip with a local patch
+  goup install tip 1234 --test # Compile a CL and run the Go tests
+  goup install tip --test=short # Compile tip and run go test -short std cmd
+  goup install --os linux --arch arm64 1.15.2 # Installed as 1.15.2-linux-arm64
+  goup install --no-set 1.15.2 # Keep the default Go
+  goup install --from-source 1.15.2 # Build from the source tarball
+  goup install --from-source 1.22.3 --variant boringcrypto --env GOEXPERIMENT=boringcrypto # Installed as 1.22.3-boringcrypto
 `,
 		RunE: runInstall,
 	}
 
 	installCmd.PersistentFlags().StringVar(&installCmdGoHostFlag, "host", GetGoHost(), "host that is used to download Go. The GOUP_GO_HOST environment variable overrides this flag.")
+	installCmd.PersistentFlags().StringVar(&installCmdOSFlag, "os", "", "target operating system, defaults to the host's")
+	installCmd.PersistentFlags().StringVar(&installCmdArchFlag, "arch", "", "target architecture, defaults to the host's. The GOUP_GO_ARCH environment variable overrides the host's architecture.")
+	installCmd.PersistentFlags().BoolVar(&installCmdSourceFlag, "from-source", false, "build Go from the source tarball, bootstrapped with a Go installed by goup")
+	installCmd.PersistentFlags().StringVar(&installCmdVariantFlag, "variant", "", "name of a build from source with other settings, installed as VERSION-VARIANT")
+	installCmd.PersistentFlags().StringArrayVar(&installCmdEnvFlag, "env", nil, "KEY=VALUE environment setting for building a --variant, e.g. GOEXPERIMENT=rangefunc or GOAMD64=v3, can be repeated")
+	installCmd.PersistentFlags().BoolVarP(&installCmdYesFlag, "yes", "y", false, "run non-interactively, assuming yes for confirmations and never reading from stdin")
+	installCmd.PersistentFlags().BoolVar(&installCmdForceFlag, "force", false, "rebuild tip even if the fetched commit has been built before")
+	installCmd.PersistentFlags().StringArrayVar(&installCmdPatchFlag, "patch", nil, "patch file to apply to tip before building, can be repeated")
+	installCmd.PersistentFlags().StringVar(&installCmdTestFlag, "test", "", "run the Go tests after building tip: full runs run.bash, short runs go test -short std cmd")
+	installCmd.PersistentFlags().Lookup("test").NoOptDefVal = testFull
+	installCmd.PersistentFlags().BoolVar(&installCmdNoSetFlag, "no-set", false, "install without setting the version as the default Go")
+	installCmd.PersistentFlags().BoolVar(&installCmdSharedFlag, "shared", false, "install into the shared store of all users, GOUP_SHARED_STORE or /opt/goup")
+	installCmd.PersistentFlags().StringVar(&installCmdCleanFlag, "clean", cleanAuto, "how to clean untracked files before building tip: auto asks when interactive and keeps them otherwise, none keeps them, force removes them")
 
 	return installCmd
 }
 
+// interactive reports whether goup may prompt the user.
+func interactive() bool {
+	if installCmdYesFlag {
+		return false
+	}
+
+	return isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+}
+
+// installPlatform returns the platform selected by the --os and --arch
+// flags.
+func installPlatform() entity.Platform {
+	p := entity.HostPlatform()
+	if installCmdOSFlag != "" {
+		p.OS = entity.NormalizeOS(installCmdOSFlag)
+	}
+	if installCmdArchFlag != "" {
+		p.Arch = entity.NormalizeArch(installCmdArchFlag)
+	}
+	return p
+}
+
+// isHostPlatform reports whether Go built for p runs on the host.
+func isHostPlatform(p entity.Platform) bool {
+	return p.String() == entity.HostPlatform().String()
+}
+
+// platformVersion returns the version directory name of version for the
+// platform p. Versions for other platforms than the host's are installed
+// side by side, e.g. as go1.15.2-linux-arm64.
+func platformVersion(version string, p entity.Platform) string {
+	if isHostPlatform(p) {
+		return version
+	}
+	return version + "-" + p.String()
+}
+
 func runInstall(cmd *cobra.Command, args []string) (err error) {
 	var release entity.Release
 	var version string
 	var svc = service.NewGoReleaseService(GetGoHost())
+	var platform = installPlatform()
+
+	if installCmdSourceFlag && !isHostPlatform(platform) {
+		return fmt.Errorf("Go can only be built from source for the host platform %s", entity.HostPlatform())
+	}
+
+	if len(installCmdPatchFlag) > 0 && (len(args) == 0 || !isTipVersion(args[0])) {
+		return errors.New("patches can only be applied to tip")
+	}
+
+	if err := checkTestSuite(installCmdTestFlag); err != nil {
+		return err
+	}
+	if installCmdTestFlag != "" && (len(args) == 0 || !isTipVersion(args[0])) {
+		return errors.New("tests can only be run after building tip")
+	}
+
+	variant, err := installVariant()
+	if err != nil {
+		return err
+	}
+
+	if installCmdSharedFlag {
+		if err := useShared(); err != nil {
+			return err
+		}
+	}
+	if variant.Name != "" && !installCmdSourceFlag && (len(args) == 0 || !isTipVersion(args[0])) {
+		return errors.New("variants are built from source, use --from-source or tip")
+	}
 
 	if len(args) == 0 {
 		release, err = svc.GetLatestRelease()
 		if err != nil {
 			return err
 		}
-		version = release.Version
+		err = installRelease(release, platform, variant)
+		version = variant.versionName(platformVersion(release.Version, platform))
 	} else {
 		version = args[0]
-		if version == "tip" {
-			var cl string
+		if tb, ok := parseTipBuild(version); ok {
+			if !isHostPlatform(platform) {
+				return fmt.Errorf("tip can only be installed for the host platform %s", entity.HostPlatform())
+			}
+
 			if len(args) > 1 {
-				cl = args[1]
+				if tb.Ref != "" {
+					return errors.New("a CL can only be applied to tip, not to tip@REF")
+				}
+				tb.CL = args[1]
 			}
-			err = installTip(cl)
+			tb.Patches = installCmdPatchFlag
+			tb.Variant = variant
+			version, err = installTip(tb)
 		} else {
 			var rl2 entity.ReleaseList
 			rl2, err = svc.GetReleaseWithFilter(version)
@@ -115,8 +220,8 @@ func runInstall(cmd *cobra.Command, args []string) (err error) {
 				return
 			}
 			release = rl2[0]
-			err = install(release)
-			version = release.Version
+			err = installRelease(release, platform, variant)
+			version = variant.versionName(platformVersion(release.Version, platform))
 		}
 	}
 
@@ -124,6 +229,21 @@ func runInstall(cmd *cobra.Command, args []string) (err error) {
 		return err
 	}
 
+	if useSharedStore {
+		if err := shareVersion(version); err != nil {
+			return err
+		}
+	}
+
+	if !isHostPlatform(platform) {
+		logger.Printf("Go for %s does not run on this machine and is not set as default", platform)
+		return nil
+	}
+
+	if installCmdNoSetFlag {
+		return nil
+	}
+
 	if err := switchVer(version); err != nil {
 		return err
 	}
@@ -131,15 +251,28 @@ func runInstall(cmd *cobra.Command, args []string) (err error) {
 	return nil
 }
 
-func switchVer(ver string) error {
-	if !strings.HasPrefix(ver, "go") {
-		ver = "go" + ver
+// installRelease installs the binary release, or builds it from source with
+// the settings of variant if the --from-source flag is set.
+func installRelease(release entity.Release, platform entity.Platform, variant buildVariant) error {
+	if installCmdSourceFlag {
+		return installFromSource(release, variant)
 	}
+	return install(release, platform)
+}
+
+func switchVer(ver string) error {
+	ver = versionName(ver)
 
 	err := symlink(ver)
 
 	if err == nil {
 		logger.Printf("Default Go is set to '%s'", ver)
+
+		if md, err := readInstallMetadata(goupVersionDir(ver)); err == nil {
+			if p, ok := md.platform(); ok && !isHostPlatform(p) {
+				logger.Warnf("%s is built for %s and may not run on this machine", ver, p)
+			}
+		}
 	}
 
 	return err
@@ -161,13 +294,16 @@ func symlink(ver string) error {
 	// ignore error, similar to rm -f
 	os.Remove(current)
 
+	// Users of the shared store may not have a goup home yet.
+	if err := os.MkdirAll(filepath.Dir(current), 0755); err != nil {
+		return err
+	}
+
 	return os.Symlink(version, current)
 }
 
-func install(release entity.Release) (err error) {
-	svc := service.NewGoReleaseService(GetGoHost())
-
-	version := release.Version
+func install(release entity.Release, platform entity.Platform) (err error) {
+	version := platformVersion(release.Version, platform)
 	targetDir := goupVersionDir(version)
 
 	if checkInstalled(targetDir) {
@@ -175,207 +311,111 @@ func install(release entity.Release) (err error) {
 		return nil
 	}
 
-	fg, err := release.ArchiveFile()
+	fg, err := release.ArchiveFileFor(platform)
 	if err != nil {
 		return
 	}
 
-	fileUrl := fg.Url(GetGoHost())
-	code, contentLength, err := svc.CheckArchiveFileExists(fileUrl)
-
+	archiveFile, fileUrl, err := downloadArchive(targetDir, fg)
 	if err != nil {
 		return err
 	}
-	if code == http.StatusNotFound {
-		return fmt.Errorf("no binary release of %v for %v/%v at %v", version, getOS(), getGoArch(), fileUrl)
-	}
-	if code != http.StatusOK {
-		return fmt.Errorf("server returned %v checking size of %v", http.StatusText(code), fileUrl)
-	}
-
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return err
-	}
-
-	archiveFile := filepath.Join(targetDir, fg.Filename)
-	if fi, err := os.Stat(archiveFile); err != nil || fi.Size() != contentLength {
-		if err != nil && !os.IsNotExist(err) {
-			// Something weird. Don't try to download.
-			return err
-		}
-		if err := svc.DownloadFile(archiveFile, fileUrl); err != nil {
-			return fmt.Errorf("error downloading %v: %v", fileUrl, err)
-		}
-		fi, err = os.Stat(archiveFile)
-		if err != nil {
-			return err
-		}
-		if fi.Size() != contentLength {
-			return fmt.Errorf("downloaded file %s size %v doesn't match server size %v", archiveFile, fi.Size(), contentLength)
-		}
-	}
 
-	wantSHA := fg.Sha256
-
-	if err := verifySHA256(archiveFile, strings.TrimSpace(wantSHA)); err != nil {
-		return fmt.Errorf("error verifying SHA256 of %v: %v", archiveFile, err)
-	}
 	logger.Printf("Unpacking %v ...", archiveFile)
-	if err := unpackArchive(targetDir, archiveFile); err != nil {
+	files, err := unpackArchive(targetDir, archiveFile)
+	if err != nil {
 		return fmt.Errorf("extracting archive %v: %v", archiveFile, err)
 	}
+	if err := writeManifest(targetDir, manifest{Archive: fg.Filename, Files: files}); err != nil {
+		return err
+	}
 
-	if err := setInstalled(targetDir); err != nil {
+	md := newInstallMetadata(version)
+	md.OS = platform.OS
+	md.Arch = platform.Arch
+	md.Host = GetGoHost()
+	md.URL = fileUrl
+	md.ArchiveSHA256 = strings.TrimSpace(fg.Sha256)
+	if err := setInstalled(targetDir, md); err != nil {
 		return err
 	}
 	logger.Printf("Success: %s installed in %v", version, targetDir)
 	return nil
 }
 
-func installTip(clNumber string) error {
-	root := goupVersionDir("gotip")
+// downloadArchive downloads the release file fg to targetDir, unless it
+// has been downloaded before, and verifies its SHA-256.
+func downloadArchive(targetDir string, fg entity.File) (archiveFile, fileUrl string, err error) {
+	svc := service.NewGoReleaseService(GetGoHost())
 
-	git := func(args ...string) error {
-		cmd := exec.Command("git", args...)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Dir = root
-		return cmd.Run()
+	fileUrl = fg.Url(GetGoHost())
+	code, contentLength, err := svc.CheckArchiveFileExists(fileUrl)
+
+	if err != nil {
+		return "", "", err
 	}
-	gitOutput := func(args ...string) ([]byte, error) {
-		cmd := exec.Command("git", args...)
-		cmd.Dir = root
-		return cmd.Output()
+	if code == http.StatusNotFound {
+		return "", "", fmt.Errorf("no release file %v at %v", fg.Filename, fileUrl)
+	}
+	if code != http.StatusOK {
+		return "", "", fmt.Errorf("server returned %v checking size of %v", http.StatusText(code), fileUrl)
 	}
 
-	if _, err := os.Stat(filepath.Join(root, ".git")); err != nil {
-		if err := os.MkdirAll(root, 0755); err != nil {
-			return fmt.Errorf("failed to create repository: %v", err)
-		}
-
-		if err := git("clone", "--depth=1", GetGoSourceGitURL(), root); err != nil {
-			return fmt.Errorf("failed to clone git repository: %v", err)
-		}
-
-		gsuURL := os.Getenv("GOUP_GO_SOURCE_GIT_URL")
-		if gsuURL == "" {
-			gsuURL = goSourceUpsteamGitURL
-		}
-
-		if err := git("remote", "add", "upstream", gsuURL); err != nil {
-			return fmt.Errorf("failed to add upstream git repository: %v", err)
-		}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", "", err
 	}
 
-	if clNumber != "" {
-		prompt := promptui.Prompt{
-			Label:     fmt.Sprintf("This will download and execute code from go.dev/cl/%s, continue", clNumber),
-			IsConfirm: true,
+	archiveFile = filepath.Join(targetDir, fg.Filename)
+	if fi, err := os.Stat(archiveFile); err != nil || fi.Size() != contentLength {
+		if err != nil && !os.IsNotExist(err) {
+			// Something weird. Don't try to download.
+			return "", "", err
 		}
-
-		if _, err := prompt.Run(); err != nil {
-			return fmt.Errorf("interrupted")
+		if err := svc.DownloadFile(archiveFile, fileUrl); err != nil {
+			return "", "", fmt.Errorf("error downloading %v: %v", fileUrl, err)
 		}
-
-		// CL is for googlesource, ls-remote against upstream
-		// ls-remote outputs a number of lines like:
-		// 2621ba2c60d05ec0b9ef37cd71e45047b004cead	refs/changes/37/227037/1
-		// 51f2af2be0878e1541d2769bd9d977a7e99db9ab	refs/changes/37/227037/2
-		// af1f3b008281c61c54a5d203ffb69334b7af007c	refs/changes/37/227037/3
-		// 6a10ebae05ce4b01cb93b73c47bef67c0f5c5f2a	refs/changes/37/227037/meta
-		refs, err := gitOutput("ls-remote", "upstream")
+		fi, err = os.Stat(archiveFile)
 		if err != nil {
-			return fmt.Errorf("failed to list remotes: %v", err)
-		}
-		r := regexp.MustCompile(`refs/changes/\d\d/` + clNumber + `/(\d+)`)
-		match := r.FindAllStringSubmatch(string(refs), -1)
-		if match == nil {
-			return fmt.Errorf("CL %v not found", clNumber)
+			return "", "", err
 		}
-		var ref string
-		var patchSet int
-		for _, m := range match {
-			ps, _ := strconv.Atoi(m[1])
-			if ps > patchSet {
-				patchSet = ps
-				ref = m[0]
-			}
-		}
-		logger.Printf("Fetching CL %v, Patch Set %v...", clNumber, patchSet)
-		if err := git("fetch", "upstream", ref); err != nil {
-			return fmt.Errorf("failed to fetch %s: %v", ref, err)
-		}
-	} else {
-		logger.Printf("Updating the go development tree...")
-		if err := git("fetch", "origin", "master"); err != nil {
-			return fmt.Errorf("failed to fetch git repository updates: %v", err)
+		if fi.Size() != contentLength {
+			return "", "", fmt.Errorf("downloaded file %s size %v doesn't match server size %v", archiveFile, fi.Size(), contentLength)
 		}
 	}
 
-	// Use checkout and a detached HEAD, because it will refuse to overwrite
-	// local changes, and warn if commits are being left behind, but will not
-	// mind if master is force-pushed upstream.
-	if err := git("-c", "advice.detachedHead=false", "checkout", "FETCH_HEAD"); err != nil {
-		return fmt.Errorf("failed to checkout git repository: %v", err)
-	}
-	// It shouldn't be the case, but in practice sometimes binary artifacts
-	// generated by earlier Go versions interfere with the build.
-	//
-	// Ask the user what to do about them if they are not gitignored. They might
-	// be artifacts that used to be ignored in previous versions, or precious
-	// uncommitted source files.
-	if err := git("clean", "-i", "-d"); err != nil {
-		return fmt.Errorf("failed to cleanup git repository: %v", err)
-	}
-	// Wipe away probably boring ignored files without bothering the user.
-	if err := git("clean", "-q", "-f", "-d", "-X"); err != nil {
-		return fmt.Errorf("failed to cleanup git repository: %v", err)
+	if err := verifySHA256(archiveFile, strings.TrimSpace(fg.Sha256)); err != nil {
+		return "", "", fmt.Errorf("error verifying SHA256 of %v: %v", archiveFile, err)
 	}
 
-	cmd := exec.Command(filepath.Join(root, "src", makeScript()))
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Dir = filepath.Join(root, "src")
-	if runtime.GOOS == "windows" {
-		// Workaround make.bat not autodetecting GOROOT_BOOTSTRAP. Issue 28641.
-		goroot, err := exec.Command("go", "env", "GOROOT").Output()
-		if err != nil {
-			return fmt.Errorf("failed to detect an existing go installation for bootstrap: %v", err)
-		}
-		cmd.Env = append(os.Environ(), "GOROOT_BOOTSTRAP="+strings.TrimSpace(string(goroot)))
-	}
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to build go: %v", err)
-	}
-
-	return nil
+	return archiveFile, fileUrl, nil
 }
 
 // unpackArchive unpacks the provided archive zip or tar.gz file to targetDir,
-// removing the "go/" prefix from file entries.
-func unpackArchive(targetDir, archiveFile string) error {
+// removing the "go/" prefix from file entries. It returns a manifest entry
+// for every regular file that was written.
+func unpackArchive(targetDir, archiveFile string) ([]manifestEntry, error) {
 	switch {
 	case strings.HasSuffix(archiveFile, ".zip"):
 		return unpackZip(targetDir, archiveFile)
 	case strings.HasSuffix(archiveFile, ".tar.gz"):
 		return unpackTarGz(targetDir, archiveFile)
 	default:
-		return errors.New("unsupported archive file")
+		return nil, errors.New("unsupported archive file")
 	}
 }
 
 // unpackTarGz is the tar.gz implementation of unpackArchive.
-func unpackTarGz(targetDir, archiveFile string) error {
+func unpackTarGz(targetDir, archiveFile string) ([]manifestEntry, error) {
 	r, err := os.Open(archiveFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer r.Close()
 	madeDir := map[string]bool{}
+	var files []manifestEntry
 	zr, err := gzip.NewReader(r)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tr := tar.NewReader(zr)
 	for {
@@ -384,10 +424,10 @@ func unpackTarGz(targetDir, archiveFile string) error {
 			break
 		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if !validRelPath(f.Name) {
-			return fmt.Errorf("tar file contained invalid name %q", f.Name)
+			return nil, fmt.Errorf("tar file contained invalid name %q", f.Name)
 		}
 		rel := filepath.FromSlash(strings.TrimPrefix(f.Name, "go/"))
 		abs := filepath.Join(targetDir, rel)
@@ -403,24 +443,30 @@ func unpackTarGz(targetDir, archiveFile string) error {
 			dir := filepath.Dir(abs)
 			if !madeDir[dir] {
 				if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
-					return err
+					return nil, err
 				}
 				madeDir[dir] = true
 			}
 			wf, err := os.OpenFile(abs, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode.Perm())
 			if err != nil {
-				return err
+				return nil, err
 			}
-			n, err := io.Copy(wf, tr)
+			hash := sha256.New()
+			n, err := io.Copy(io.MultiWriter(wf, hash), tr)
 			if closeErr := wf.Close(); closeErr != nil && err == nil {
 				err = closeErr
 			}
 			if err != nil {
-				return fmt.Errorf("error writing to %s: %v", abs, err)
+				return nil, fmt.Errorf("error writing to %s: %v", abs, err)
 			}
 			if n != f.Size {
-				return fmt.Errorf("only wrote %d bytes to %s; expected %d", n, abs, f.Size)
+				return nil, fmt.Errorf("only wrote %d bytes to %s; expected %d", n, abs, f.Size)
 			}
+			entry, err := newManifestEntry(targetDir, abs, hash)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, entry)
 			if !f.ModTime.IsZero() {
 				if err := os.Chtimes(abs, f.ModTime, f.ModTime); err != nil {
 					// benign error. Gerrit doesn't even set the
@@ -433,79 +479,139 @@ func unpackTarGz(targetDir, archiveFile string) error {
 			}
 		case mode.IsDir():
 			if err := os.MkdirAll(abs, 0755); err != nil {
-				return err
+				return nil, err
 			}
 			madeDir[abs] = true
 		default:
-			return fmt.Errorf("tar file entry %s contained unsupported file type %v", f.Name, mode)
+			return nil, fmt.Errorf("tar file entry %s contained unsupported file type %v", f.Name, mode)
 		}
 	}
-	return nil
+	return files, nil
 }
 
 // unpackZip is the zip implementation of unpackArchive.
-func unpackZip(targetDir, archiveFile string) error {
+func unpackZip(targetDir, archiveFile string) ([]manifestEntry, error) {
 	zr, err := zip.OpenReader(archiveFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer zr.Close()
 
+	var files []manifestEntry
 	for _, f := range zr.File {
 		name := strings.TrimPrefix(f.Name, "go/")
 
 		outpath := filepath.Join(targetDir, name)
 		if f.FileInfo().IsDir() {
 			if err := os.MkdirAll(outpath, 0755); err != nil {
-				return err
+				return nil, err
 			}
 			continue
 		}
 
 		rc, err := f.Open()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// File
 		if err := os.MkdirAll(filepath.Dir(outpath), 0755); err != nil {
-			return err
+			return nil, err
 		}
 		out, err := os.OpenFile(outpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
 		if err != nil {
-			return err
+			return nil, err
 		}
-		_, err = io.Copy(out, rc)
+		hash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, hash), rc)
 		rc.Close()
 		if err != nil {
 			out.Close()
-			return err
+			return nil, err
 		}
 		if err := out.Close(); err != nil {
-			return err
+			return nil, err
+		}
+		entry, err := newManifestEntry(targetDir, outpath, hash)
+		if err != nil {
+			return nil, err
 		}
+		files = append(files, entry)
 	}
-	return nil
+	return files, nil
 }
 
 // verifySHA256 reports whether the named file has contents with
 // SHA-256 of the given wantHex value.
 func verifySHA256(file, wantHex string) error {
-	f, err := os.Open(file)
+	got, err := fileSHA256(file)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	hash := sha256.New()
-	if _, err := io.Copy(hash, f); err != nil {
+	if got != wantHex {
+		return fmt.Errorf("%s corrupt? does not have expected SHA-256 of %v", file, wantHex)
+	}
+	return nil
+}
+
+// runMake builds the Go source tree in root with the make script, adding
+// env to the environment of the build.
+// The full output is written to buildLogFile in root, see runLogged.
+func runMake(root string, env []string) error {
+	logFile := filepath.Join(root, buildLogFile)
+
+	cmd := exec.Command(filepath.Join(root, "src", makeScript()))
+	cmd.Dir = filepath.Join(root, "src")
+	cmd.Env = append(os.Environ(), env...)
+	if err := runLogged(cmd, logFile); err != nil {
+		return fmt.Errorf("failed to build go: %v, the full build log is at %s", err, logFile)
+	}
+
+	return nil
+}
+
+// runLogged runs cmd with its output written to logFile. The output is
+// only shown while running when goup runs interactively, otherwise the end
+// of the log is printed if cmd fails.
+func runLogged(cmd *exec.Cmd, logFile string) error {
+	log, err := os.Create(logFile)
+	if err != nil {
 		return err
 	}
-	if fmt.Sprintf("%x", hash.Sum(nil)) != wantHex {
-		return fmt.Errorf("%s corrupt? does not have expected SHA-256 of %v", file, wantHex)
+	defer log.Close()
+
+	var out io.Writer = log
+	if interactive() {
+		out = io.MultiWriter(os.Stdout, log)
+	}
+
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		if out == log {
+			fmt.Fprintln(os.Stderr, tailFile(logFile, 20))
+		}
+		return err
 	}
+
 	return nil
 }
 
+// tailFile returns the last n lines of file, or nothing if it can't be
+// read.
+func tailFile(file string, n int) string {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 func makeScript() string {
 	switch runtime.GOOS {
 	case "plan9":
@@ -517,8 +623,12 @@ func makeScript() string {
 	}
 }
 
-// unpackedOkay is a sentinel zero-byte file to indicate that the Go
-// version was downloaded and unpacked successfully.
+// buildLogFile is the output of the last build of a Go source tree.
+const buildLogFile = ".goup-build.log"
+
+// unpackedOkay is a sentinel zero-byte file that older goup releases wrote
+// to indicate that the Go version was downloaded and unpacked successfully.
+// It has been replaced by installMetadataFile.
 const unpackedOkay = ".unpacked-success"
 
 func validRelPath(p string) bool {
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func linkCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "link <NAME> <GOROOT>",
+		Short: "Register a Go installed outside of goup",
+		Long: `Register a GOROOT that was not installed by goup, e.g. a Go built by hand or
+a vendor-patched Go, under a name. It can then be used like any installed Go
+version by set, list and exec.`,
+		Example: `
+  goup link mygo /path/to/goroot
+  goup set mygo
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: runLink,
+	}
+}
+
+func unlinkCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unlink <NAME>...",
+		Short: "Unregister a Go registered with link",
+		Long:  "Unregister a Go registered with link. The GOROOT itself is left untouched.",
+		Example: `
+  goup unlink mygo
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runUnlink,
+	}
+}
+
+func runLink(cmd *cobra.Command, args []string) error {
+	name, goroot := args[0], args[1]
+
+	if strings.ContainsAny(name, `/\`) || isTipVersion(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+
+	goroot, err := filepath.Abs(goroot)
+	if err != nil {
+		return err
+	}
+
+	out, err := exec.Command(filepath.Join(goroot, "bin", goExe()), "version").Output()
+	if err != nil {
+		return fmt.Errorf("%s is not a working GOROOT: %v", goroot, err)
+	}
+
+	ver := versionName(name)
+	dir := goupVersionDir(ver)
+	if _, err := os.Lstat(dir); err == nil {
+		return fmt.Errorf("%s already exists in %s", strings.TrimPrefix(ver, "go"), dir)
+	}
+
+	if err := os.MkdirAll(GoupDir(), 0755); err != nil {
+		return err
+	}
+	if err := os.Symlink(goroot, dir); err != nil {
+		return err
+	}
+
+	logger.Printf("Linked %s to %s: %s", strings.TrimPrefix(ver, "go"), goroot, strings.TrimSpace(string(out)))
+	return nil
+}
+
+func runUnlink(cmd *cobra.Command, args []string) error {
+	current, _ := currentGoVersion()
+
+	for _, name := range args {
+		ver := versionName(name)
+		dir := goupVersionDir(ver)
+
+		fi, err := os.Lstat(dir)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s is not registered with `goup link`", name)
+		}
+
+		logger.Printf("Unlinking %s", name)
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+
+		if ver == current {
+			logger.Warnf("%s was the default Go, set a new default with `goup set`", name)
+		}
+	}
+
+	return nil
+}
+
+// isGoroot reports whether dir contains a Go distribution.
+func isGoroot(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, "bin", goExe()))
+	return err == nil && !fi.IsDir()
+}
@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/olekukonko/tablewriter"
@@ -12,14 +15,29 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	listCmdLongFlag bool
+	listCmdJSONFlag bool
+)
+
 func listCmd() *cobra.Command {
-	return &cobra.Command{
+	listCmd := &cobra.Command{
 		Use:     "list",
 		Aliases: []string{"ls"},
 		Short:   "List all installed Go",
 		Long:    "List all installed Go versions.",
-		RunE:    runList,
+		Example: `
+  goup list
+  goup list -l
+  goup list --json
+`,
+		RunE: runList,
 	}
+
+	listCmd.PersistentFlags().BoolVarP(&listCmdLongFlag, "long", "l", false, "Show size, installation date, origin and test results")
+	listCmd.PersistentFlags().BoolVar(&listCmdJSONFlag, "json", false, "Print the installed versions and their metadata as JSON")
+
+	return listCmd
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -28,22 +46,103 @@ func runList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if listCmdJSONFlag {
+		return printGoVersJSON(vers)
+	}
+
 	if len(vers) == 0 {
 		showGoIfExist()
 		return nil
 	}
 
+	// Show the built commit when there are tip builds, and the build
+	// settings when there are variants.
+	mds := make([]*installMetadata, len(vers))
+	var hasCommits, hasVariants, hasTests, hasShared bool
+	for i, ver := range vers {
+		hasShared = hasShared || ver.Store == storeShared
+		if md, err := readInstallMetadata(ver.Dir); err == nil {
+			mds[i] = &md
+			hasCommits = hasCommits || md.Git != nil
+			hasVariants = hasVariants || (md.Build != nil && md.Build.Variant != "")
+			hasTests = hasTests || md.Test != nil
+		}
+	}
+
+	header := []string{"Version", "Active"}
+	if hasShared {
+		header = append(header, "Store")
+	}
+	if hasCommits {
+		header = append(header, "Commit")
+	}
+	if hasVariants {
+		header = append(header, "Variant")
+	}
+	if listCmdLongFlag {
+		header = append(header, "Platform", "Size", "Installed", "Origin")
+		if hasTests {
+			header = append(header, "Tests")
+		}
+	}
+
 	table := tablewriter.NewTable(os.Stdout,
-		tablewriter.WithHeader([]string{"Version", "Active"}),
+		tablewriter.WithHeader(header),
 		tablewriter.WithAlignment([]tw.Align{tw.AlignCenter}),
 	)
 
-	for _, ver := range vers {
+	for i, ver := range vers {
+		md := mds[i]
+
+		var active string
 		if ver.Current {
-			table.Append([]string{ver.Ver, "*"})
-		} else {
-			table.Append([]string{ver.Ver, ""})
+			active = "*"
 		}
+		row := []string{ver.Ver, active}
+		if hasShared {
+			row = append(row, ver.Store)
+		}
+		if hasCommits {
+			var commit string
+			if md != nil && md.Git != nil {
+				commit = shortCommit(md.Git.Commit)
+			}
+			row = append(row, commit)
+		}
+		if hasVariants {
+			var variant string
+			if md != nil && md.Build != nil {
+				variant = strings.Join(md.Build.Env, " ")
+			}
+			row = append(row, variant)
+		}
+
+		if listCmdLongFlag {
+			var platform, size, installed, origin string
+			if n, err := dirSize(ver.Dir); err == nil {
+				size = formatSize(n)
+			}
+			if md != nil {
+				if p, ok := md.platform(); ok {
+					platform = p.String()
+				}
+				installed = md.InstalledAt.Local().Format("2006-01-02 15:04")
+				origin = md.origin()
+			}
+			if ver.Link != "" {
+				origin = "link to " + ver.Link
+			}
+			row = append(row, platform, size, installed, origin)
+			if hasTests {
+				var tests string
+				if md != nil {
+					tests = md.Test.status()
+				}
+				row = append(row, tests)
+			}
+		}
+
+		table.Append(row)
 	}
 
 	table.Render()
@@ -51,48 +150,155 @@ func runList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func printGoVersJSON(vers []goVer) error {
+	type jsonGoVer struct {
+		Version  string           `json:"version"`
+		Active   bool             `json:"active"`
+		Path     string           `json:"path"`
+		Store    string           `json:"store"`
+		Link     string           `json:"link,omitempty"`
+		Size     int64            `json:"size"`
+		Metadata *installMetadata `json:"metadata,omitempty"`
+	}
+
+	out := make([]jsonGoVer, 0, len(vers))
+	for _, ver := range vers {
+		v := jsonGoVer{
+			Version: ver.Ver,
+			Active:  ver.Current,
+			Path:    ver.Dir,
+			Store:   ver.Store,
+			Link:    ver.Link,
+		}
+		if n, err := dirSize(ver.Dir); err == nil {
+			v.Size = n
+		}
+		if md, err := readInstallMetadata(ver.Dir); err == nil {
+			v.Metadata = &md
+		}
+		out = append(out, v)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// dirSize returns the total size of the regular files below dir.
+func dirSize(dir string) (int64, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func showGoIfExist() {
 	goBin, err := exec.LookPath("go")
 	if err == nil {
-		fmt.Printf("No Go is installed by Goup. Using system Go %q.\n", goBin)
+		fmt.Printf("No Go is installed by Goup. Using system Go %q, adopt it with `goup adopt`.\n", goBin)
 	} else {
 		fmt.Println("No Go is installed by Goup.")
 	}
 }
 
 type goVer struct {
-	Ver     string
+	Ver string
+	Dir string
+	// Link is the GOROOT the version directory links to, if any.
+	Link string
+	// Store is where the version lives, the goup home or the shared
+	// store.
+	Store   string
 	Current bool
 }
 
+// listGoVers returns the versions in the goup home and the shared store.
+// Versions in the goup home shadow those of the same name in the shared
+// store.
 func listGoVers() ([]goVer, error) {
-	baseDir := GoupDir()
-	files, err := os.ReadDir(baseDir)
-	if err != nil {
-		return nil, err
-	}
-
 	current, err := currentGoVersion()
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
 
 	var vers []goVer
-	for _, file := range files {
-		if !file.IsDir() {
+	seen := make(map[string]bool)
+	for _, store := range versionStores() {
+		files, err := os.ReadDir(store.Dir)
+		if err != nil {
+			if store.Name != storeUser || !os.IsNotExist(err) {
+				return nil, err
+			}
+			// Users of the shared store may not have a goup home.
 			continue
 		}
-		if strings.HasPrefix(file.Name(), "go") {
-			// will not set installed for the gotip, so should not check installed for it
-			if file.Name() != "gotip" && !checkInstalled(filepath.Join(baseDir, file.Name())) {
+
+		for _, file := range files {
+			if !strings.HasPrefix(file.Name(), "go") || seen[file.Name()] {
+				continue
+			}
+			if store.Name == storeShared && !useSharedStore && isMutableVersion(file.Name()) {
+				continue
+			}
+
+			dir := filepath.Join(store.Dir, file.Name())
+			var link string
+			switch {
+			case file.Type()&fs.ModeSymlink != 0:
+				// A GOROOT registered with goup link.
+				if link, err = os.Readlink(dir); err != nil || !isGoroot(dir) {
+					continue
+				}
+			case !file.IsDir():
+				continue
+			case file.Name() != tipVersion && !checkInstalled(dir):
+				// gotip built by older goup releases has no install
+				// metadata, so should not check installed for it
 				continue
 			}
+
+			seen[file.Name()] = true
 			vers = append(vers, goVer{
 				Ver:     strings.TrimPrefix(file.Name(), "go"),
+				Dir:     dir,
+				Link:    link,
+				Store:   store.Name,
 				Current: current == file.Name(),
 			})
 		}
 	}
+	slices.SortFunc(vers, func(a, b goVer) int { return strings.Compare(a.Ver, b.Ver) })
 
 	return vers, nil
 }
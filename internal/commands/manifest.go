@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// manifestFile records every file unpacked from a Go archive so that later
+// modifications of the GOROOT can be detected by `goup verify`.
+const manifestFile = ".goup-manifest.json"
+
+type manifest struct {
+	// Archive is the file name of the archive, cached in the version
+	// directory, that the files were extracted from.
+	Archive string          `json:"archive"`
+	Files   []manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	// Path is slash-separated and relative to the version directory.
+	Path   string      `json:"path"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size"`
+	Sha256 string      `json:"sha256"`
+}
+
+// newManifestEntry describes the file abs that was just written below
+// targetDir, with h holding the SHA-256 of its content.
+func newManifestEntry(targetDir, abs string, h hash.Hash) (manifestEntry, error) {
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+	rel, err := filepath.Rel(targetDir, abs)
+	if err != nil {
+		return manifestEntry{}, err
+	}
+
+	return manifestEntry{
+		Path:   filepath.ToSlash(rel),
+		Mode:   fi.Mode().Perm(),
+		Size:   fi.Size(),
+		Sha256: fmt.Sprintf("%x", h.Sum(nil)),
+	}, nil
+}
+
+// scanManifest records the files below targetDir, e.g. after a Go source
+// tree has been built in it.
+func scanManifest(targetDir, archive string) (manifest, error) {
+	m := manifest{Archive: archive}
+
+	err := filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(targetDir, path)
+		if err != nil {
+			return err
+		}
+		if isGoupFile(filepath.ToSlash(rel), m) {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+
+		entry, err := newManifestEntry(targetDir, path, h)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, entry)
+
+		return nil
+	})
+
+	return m, err
+}
+
+func writeManifest(targetDir string, m manifest) error {
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(targetDir, manifestFile), b, 0644)
+}
+
+func readManifest(targetDir string) (manifest, error) {
+	var m manifest
+
+	b, err := os.ReadFile(filepath.Join(targetDir, manifestFile))
+	if err != nil {
+		return m, err
+	}
+
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// manifestReport lists the differences between a version directory and its
+// manifest. All paths are slash-separated and relative to the directory.
+type manifestReport struct {
+	Modified []string
+	Missing  []string
+	Extra    []string
+}
+
+func (r manifestReport) Len() int {
+	return len(r.Modified) + len(r.Missing) + len(r.Extra)
+}
+
+// checkManifest compares the files below targetDir with the manifest m.
+// Files maintained by goup itself are never reported as extra.
+func checkManifest(targetDir string, m manifest) (manifestReport, error) {
+	var report manifestReport
+
+	known := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		known[f.Path] = true
+
+		fi, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				report.Missing = append(report.Missing, f.Path)
+				continue
+			}
+			return report, err
+		}
+
+		// Permission bits are meaningless on Windows.
+		if fi.Size() != f.Size || (runtime.GOOS != "windows" && fi.Mode().Perm() != f.Mode) {
+			report.Modified = append(report.Modified, f.Path)
+			continue
+		}
+
+		sum, err := fileSHA256(filepath.Join(targetDir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return report, err
+		}
+		if sum != f.Sha256 {
+			report.Modified = append(report.Modified, f.Path)
+		}
+	}
+
+	err := filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(targetDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !known[rel] && !isGoupFile(rel, m) {
+			report.Extra = append(report.Extra, rel)
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// isGoupFile reports whether rel is a file that goup keeps in a version
+// directory next to the unpacked Go distribution.
+func isGoupFile(rel string, m manifest) bool {
+	switch rel {
+	case unpackedOkay, installMetadataFile, manifestFile, buildLogFile, testLogFile, m.Archive:
+		return true
+	default:
+		return false
+	}
+}
+
+func fileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/owenthereal/goup/internal/entity"
+)
+
+// installMetadataFile describes how a Go version was installed. It replaces
+// the zero-byte unpackedOkay sentinel, which is still recognized for Go
+// versions installed by older goup releases.
+const installMetadataFile = ".goup-install.json"
+
+type installMetadata struct {
+	Version string `json:"version"`
+	// Host and URL are where the archive was downloaded from. For tip, URL
+	// is the git repository the source was fetched from, and for adopted
+	// Go the GOROOT it was copied from.
+	Host          string    `json:"host,omitempty"`
+	URL           string    `json:"url,omitempty"`
+	ArchiveSHA256 string    `json:"archive_sha256,omitempty"`
+	InstalledAt   time.Time `json:"installed_at"`
+	GoupVersion   string    `json:"goup_version"`
+	OS            string    `json:"os"`
+	Arch          string    `json:"arch"`
+
+	Git   *gitMetadata   `json:"git,omitempty"`
+	Build *buildMetadata `json:"build,omitempty"`
+	Test  *testMetadata  `json:"test,omitempty"`
+}
+
+// gitMetadata describes the source of a Go built from the git repository.
+type gitMetadata struct {
+	Commit string `json:"commit"`
+	// Ref is the branch, tag or commit that was requested, empty for
+	// master.
+	Ref      string `json:"ref,omitempty"`
+	CL       string `json:"cl,omitempty"`
+	PatchSet int    `json:"patch_set,omitempty"`
+	// Patches are the local patch files applied on top of the commit.
+	Patches []patchMetadata `json:"patches,omitempty"`
+}
+
+type patchMetadata struct {
+	File   string `json:"file"`
+	Sha256 string `json:"sha256"`
+}
+
+// buildMetadata describes a Go that goup built from source.
+type buildMetadata struct {
+	// Bootstrap is the GOROOT_BOOTSTRAP toolchain used for the build.
+	Bootstrap string `json:"bootstrap,omitempty"`
+	// Variant and Env are the name and the environment settings of a
+	// variant build, see buildVariant.
+	Variant string   `json:"variant,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	// Cache is the key of the build cache entry the build output was
+	// restored from instead of building.
+	Cache string `json:"cache,omitempty"`
+}
+
+// testMetadata is the result of running the Go tests after a build.
+type testMetadata struct {
+	// Suite is the test suite that was run, testFull or testShort.
+	Suite    string    `json:"suite"`
+	Passed   bool      `json:"passed"`
+	Duration string    `json:"duration"`
+	Log      string    `json:"log"`
+	RanAt    time.Time `json:"ran_at"`
+}
+
+// status is a short human readable description of the test result.
+func (tm *testMetadata) status() string {
+	if tm == nil {
+		return ""
+	}
+	if tm.Passed {
+		return fmt.Sprintf("%s passed (%s)", tm.Suite, tm.Duration)
+	}
+	return fmt.Sprintf("%s FAILED (%s)", tm.Suite, tm.Duration)
+}
+
+func newBuildMetadata(bootstrap string, v buildVariant) *buildMetadata {
+	bm := &buildMetadata{
+		Variant: v.Name,
+		Env:     v.Env,
+	}
+	if bootstrap != "" {
+		bm.Bootstrap = filepath.Base(bootstrap)
+	}
+	return bm
+}
+
+func newInstallMetadata(version string) installMetadata {
+	p := entity.HostPlatform()
+
+	return installMetadata{
+		Version:     version,
+		InstalledAt: time.Now().UTC(),
+		GoupVersion: Version,
+		OS:          p.OS,
+		Arch:        p.Arch,
+	}
+}
+
+// platform returns the platform the installed Go runs on, which is unknown
+// for Go versions installed by older goup releases.
+func (md installMetadata) platform() (entity.Platform, bool) {
+	if md.OS == "" || md.Arch == "" {
+		return entity.Platform{}, false
+	}
+	return entity.Platform{OS: md.OS, Arch: md.Arch}, true
+}
+
+// origin is a short human readable description of where the Go version
+// came from.
+func (md installMetadata) origin() string {
+	switch {
+	case md.Git != nil:
+		var origin string
+		switch {
+		case md.Git.CL != "":
+			origin = fmt.Sprintf("CL %s/%d @ %s", md.Git.CL, md.Git.PatchSet, shortCommit(md.Git.Commit))
+		case md.Git.Ref != "":
+			origin = fmt.Sprintf("%s @ %s", md.Git.Ref, shortCommit(md.Git.Commit))
+		default:
+			origin = fmt.Sprintf("git @ %s", shortCommit(md.Git.Commit))
+		}
+		if n := len(md.Git.Patches); n > 0 {
+			origin += fmt.Sprintf(" + %d patches", n)
+		}
+		return origin
+	case md.Build != nil && md.Host != "":
+		return md.Host + " (source)"
+	case md.Host != "":
+		return md.Host
+	default:
+		return md.URL
+	}
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// readInstallMetadata reads the metadata of the Go version installed in
+// targetDir. For versions that only have the legacy unpackedOkay sentinel,
+// the metadata is limited to the version and the time of installation.
+func readInstallMetadata(targetDir string) (installMetadata, error) {
+	var md installMetadata
+
+	b, err := os.ReadFile(filepath.Join(targetDir, installMetadataFile))
+	if err == nil {
+		err = json.Unmarshal(b, &md)
+		return md, err
+	}
+	if !os.IsNotExist(err) {
+		return md, err
+	}
+
+	fi, err := os.Stat(filepath.Join(targetDir, unpackedOkay))
+	if err != nil {
+		return md, err
+	}
+
+	md.Version = filepath.Base(targetDir)
+	md.InstalledAt = fi.ModTime().UTC()
+	return md, nil
+}
+
+func writeInstallMetadata(targetDir string, md installMetadata) error {
+	b, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(targetDir, installMetadataFile), b, 0644)
+}
@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateCmdFromFlag   string
+	migrateCmdCopyFlag   bool
+	migrateCmdDryRunFlag bool
+)
+
+// goManager is another Go version manager goup can migrate from.
+type goManager struct {
+	// goroots returns the GOROOTs of the installed versions.
+	goroots func() ([]string, error)
+	// active returns the GOROOT of the default version, or "" if there
+	// is none.
+	active func() (string, error)
+}
+
+var goManagers = map[string]goManager{
+	"gvm":   {goroots: gvmGoroots, active: gvmActive},
+	"goenv": {goroots: goenvGoroots, active: goenvActive},
+	"asdf":  {goroots: asdfGoroots, active: asdfActive},
+}
+
+func migrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate --from <gvm|goenv|asdf>",
+		Short: "Import the Go versions of another version manager",
+		Long: `Import all Go versions installed by gvm, goenv or asdf and make the default
+version of the manager the default Go. Like with goup adopt, the versions are
+registered in place unless --copy is set, which copies them into the goup home
+so that the other manager can be uninstalled.`,
+		Example: `
+  goup migrate --from gvm --dry-run
+  goup migrate --from goenv
+  goup migrate --from asdf --copy
+`,
+		Args: cobra.NoArgs,
+		RunE: runMigrate,
+	}
+
+	migrateCmd.PersistentFlags().StringVar(&migrateCmdFromFlag, "from", "", "version manager to migrate from: gvm, goenv or asdf")
+	migrateCmd.PersistentFlags().BoolVar(&migrateCmdCopyFlag, "copy", false, "copy the versions into the goup home instead of registering them in place")
+	migrateCmd.PersistentFlags().BoolVar(&migrateCmdDryRunFlag, "dry-run", false, "print the versions that would be imported without importing them")
+	migrateCmd.MarkPersistentFlagRequired("from")
+
+	return migrateCmd
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	name := migrateCmdFromFlag
+	m, ok := goManagers[name]
+	if !ok {
+		return fmt.Errorf("unsupported version manager %q, must be one of gvm, goenv or asdf", name)
+	}
+
+	goroots, err := m.goroots()
+	if err != nil {
+		return fmt.Errorf("failed to find the Go versions of %s: %v", name, err)
+	}
+	if len(goroots) == 0 {
+		logger.Printf("No Go versions of %s are found", name)
+		return nil
+	}
+
+	active, err := m.active()
+	if err != nil {
+		logger.Warnf("failed to find the default Go of %s: %v", name, err)
+	}
+
+	var activeVer string
+	for _, goroot := range goroots {
+		ver, err := adoptGoroot(goroot, migrateCmdCopyFlag, migrateCmdDryRunFlag)
+		if err != nil && !errors.Is(err, errAdopted) {
+			logger.Warnf("failed to import %s: %v", goroot, err)
+			continue
+		}
+		if active != "" && sameDir(goroot, active) {
+			activeVer = ver
+		}
+	}
+
+	if activeVer != "" {
+		if migrateCmdDryRunFlag {
+			fmt.Printf("Would set the default Go to %s\n", activeVer)
+		} else if err := switchVer(activeVer); err != nil {
+			return err
+		}
+	}
+
+	if !migrateCmdDryRunFlag {
+		logger.Printf("Imported the Go versions of %s. Remove its lines from your shell startup files so that it doesn't override goup.", name)
+	}
+
+	return nil
+}
+
+// managerRoot returns the directory in env, or dir in the home directory.
+func managerRoot(env, dir string) string {
+	if root := os.Getenv(env); root != "" {
+		return root
+	}
+	return filepath.Join(homedir, dir)
+}
+
+// globGoroots returns the GOROOTs matching pattern in sorted order.
+func globGoroots(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var goroots []string
+	for _, m := range matches {
+		if isGoroot(m) {
+			goroots = append(goroots, m)
+		}
+	}
+	sort.Strings(goroots)
+
+	return goroots, nil
+}
+
+func gvmGoroots() ([]string, error) {
+	return globGoroots(filepath.Join(managerRoot("GVM_ROOT", ".gvm"), "gos", "*"))
+}
+
+// gvmActive reads GOROOT from the default environment of gvm, written by
+// gvm use --default.
+func gvmActive() (string, error) {
+	root := managerRoot("GVM_ROOT", ".gvm")
+	f, err := os.Open(filepath.Join(root, "environments", "default"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// e.g. export GOROOT; GOROOT="$GVM_ROOT/gos/go1.21"
+		_, value, ok := strings.Cut(s.Text(), `GOROOT="`)
+		if !ok {
+			continue
+		}
+		value, _, _ = strings.Cut(value, `"`)
+		return os.Expand(value, func(key string) string {
+			if key == "GVM_ROOT" {
+				return root
+			}
+			return os.Getenv(key)
+		}), nil
+	}
+
+	return "", s.Err()
+}
+
+func goenvGoroots() ([]string, error) {
+	return globGoroots(filepath.Join(managerRoot("GOENV_ROOT", ".goenv"), "versions", "*"))
+}
+
+// goenvActive reads the global version of goenv, written by goenv global.
+func goenvActive() (string, error) {
+	root := managerRoot("GOENV_ROOT", ".goenv")
+	ver, err := readFirstField(filepath.Join(root, "version"), "")
+	if err != nil || ver == "" || ver == "system" {
+		return "", err
+	}
+	return filepath.Join(root, "versions", ver), nil
+}
+
+func asdfGoroots() ([]string, error) {
+	return globGoroots(filepath.Join(managerRoot("ASDF_DATA_DIR", ".asdf"), "installs", "golang", "*", "go"))
+}
+
+// asdfActive reads the golang version of the global .tool-versions file
+// of asdf.
+func asdfActive() (string, error) {
+	file := os.Getenv("ASDF_DEFAULT_TOOL_VERSIONS_FILENAME")
+	if file == "" {
+		file = ".tool-versions"
+	}
+	ver, err := readFirstField(filepath.Join(homedir, file), "golang")
+	if err != nil || ver == "" || ver == "system" {
+		return "", err
+	}
+	return filepath.Join(managerRoot("ASDF_DATA_DIR", ".asdf"), "installs", "golang", ver, "go"), nil
+}
+
+// readFirstField returns the first field after key of the first line of
+// file starting with key, or of the first line if key is empty. A missing
+// file has no fields.
+func readFirstField(file, key string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if key == "" {
+			if len(fields) > 0 {
+				return fields[0], nil
+			}
+			continue
+		}
+		if len(fields) > 1 && fields[0] == key {
+			return fields[1], nil
+		}
+	}
+
+	return "", s.Err()
+}
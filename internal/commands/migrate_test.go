@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFirstField(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".tool-versions")
+	content := "# tools\n\nnodejs 20.11.0\ngolang 1.22.0 1.21.6\ngolang 1.20.0\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		key  string
+		want string
+	}{
+		{"", "#"},
+		{"golang", "1.22.0"},
+		{"nodejs", "20.11.0"},
+		{"python", ""},
+	} {
+		if got, err := readFirstField(file, tt.key); err != nil || got != tt.want {
+			t.Errorf("readFirstField(%q) = %q, %v, want %q", tt.key, got, err, tt.want)
+		}
+	}
+
+	if got, err := readFirstField(filepath.Join(t.TempDir(), "version"), ""); err != nil || got != "" {
+		t.Errorf("readFirstField() of a missing file = %q, %v, want no field", got, err)
+	}
+}
+
+func TestGvmActive(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GVM_ROOT", root)
+
+	if got, err := gvmActive(); err != nil || got != "" {
+		t.Errorf("gvmActive() without a default = %q, %v, want none", got, err)
+	}
+
+	env := filepath.Join(root, "environments", "default")
+	if err := os.MkdirAll(filepath.Dir(env), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `export GVM_ROOT; GVM_ROOT="` + root + `"
+export gvm_go_name; gvm_go_name="go1.21"
+export GOROOT; GOROOT="$GVM_ROOT/gos/go1.21"
+export PATH; PATH="$GVM_ROOT/gos/go1.21/bin:$PATH"
+`
+	if err := os.WriteFile(env, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join(root, "gos", "go1.21")
+	if got, err := gvmActive(); err != nil || got != want {
+		t.Errorf("gvmActive() = %q, %v, want %q", got, err, want)
+	}
+}
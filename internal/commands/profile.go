@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Marker comments around the lines goup adds to shell startup files, so
+// that goup deinit can remove them. All supported shells use # for
+// comments.
+const (
+	profileBeginMarker = "# >>> goup >>>"
+	profileEndMarker   = "# <<< goup <<<"
+)
+
+// backupTimeFormat is the timestamp of backups of edited files.
+const backupTimeFormat = "20060102150405"
+
+// fileEdit is a change of a file that can be shown as a diff before it is
+// made.
+type fileEdit struct {
+	File   string
+	Exists bool
+	Old    string
+	New    string
+	// Remove removes the file instead of writing New.
+	Remove bool
+	// Backup copies an existing file to a timestamped backup before
+	// changing it.
+	Backup bool
+}
+
+// newFileEdit returns an edit of file, to be filled in with New or Remove.
+func newFileEdit(file string, backup bool) (fileEdit, error) {
+	e := fileEdit{File: file, Backup: backup}
+
+	b, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return e, err
+	}
+	e.Exists = err == nil
+	e.Old = string(b)
+	e.New = e.Old
+
+	return e, nil
+}
+
+func (e fileEdit) changed() bool {
+	if e.Remove {
+		return e.Exists
+	}
+	return !e.Exists || e.Old != e.New
+}
+
+// diff returns the change as a unified diff.
+func (e fileEdit) diff() string {
+	oldName, newName := e.File, e.File
+	if !e.Exists {
+		oldName = ""
+	}
+	newContent := e.New
+	if e.Remove {
+		newName, newContent = "", ""
+	}
+
+	return unifiedDiff(oldName, newName, e.Old, newContent)
+}
+
+// apply makes the change, backing up the file first if needed.
+func (e fileEdit) apply() error {
+	if !e.changed() {
+		return nil
+	}
+
+	if e.Exists && e.Backup {
+		backup := backupFile(e.File)
+		if err := copyFile(e.File, backup); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", e.File, err)
+		}
+		logger.Printf("Backed up %s to %s", e.File, backup)
+	}
+
+	if e.Remove {
+		return os.Remove(e.File)
+	}
+
+	// Startup files such as fish's conf.d/goup.fish may be in a
+	// directory of their own.
+	if err := os.MkdirAll(filepath.Dir(e.File), 0755); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0600)
+	if fi, err := os.Stat(e.File); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	return os.WriteFile(e.File, []byte(e.New), perm)
+}
+
+// backupFile returns a timestamped backup file of file that doesn't exist
+// yet.
+func backupFile(file string) string {
+	backup := file + ".goup-backup-" + time.Now().Format(backupTimeFormat)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			return backup
+		}
+		backup = fmt.Sprintf("%s.goup-backup-%s-%d", file, time.Now().Format(backupTimeFormat), i)
+	}
+}
+
+// addMarkedLine returns content with line between goup's markers. An
+// existing block of goup is replaced, and an existing line or legacy line
+// without markers, as written by older versions of goup, is replaced by the
+// block. Otherwise the block is appended.
+func addMarkedLine(content, line string, legacy ...string) string {
+	lines := splitLines(content)
+	block := []string{profileBeginMarker, line, profileEndMarker}
+
+	if begin, end, ok := findMarkedBlock(lines); ok {
+		if slices.Equal(lines[begin:end+1], block) {
+			return content
+		}
+		return joinLines(append(append(lines[:begin:begin], block...), lines[end+1:]...))
+	}
+
+	for i, l := range lines {
+		if l == line || slices.Contains(legacy, l) {
+			// Lines before and after are kept as they are.
+			return joinLines(append(append(lines[:i:i], block...), lines[i+1:]...))
+		}
+	}
+
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return joinLines(append(lines, block...))
+}
+
+// removeMarkedLines returns content without goup's blocks and without the
+// lines in legacy, which older versions of goup wrote without markers.
+func removeMarkedLines(content string, legacy ...string) string {
+	lines := splitLines(content)
+	for {
+		begin, end, ok := findMarkedBlock(lines)
+		if !ok {
+			break
+		}
+		// Remove the empty line goup added before the block as well.
+		if begin > 0 && lines[begin-1] == "" {
+			begin--
+		}
+		lines = append(lines[:begin:begin], lines[end+1:]...)
+	}
+
+	var kept []string
+	for _, l := range lines {
+		if !slices.Contains(legacy, l) {
+			kept = append(kept, l)
+		}
+	}
+
+	return joinLines(kept)
+}
+
+// findMarkedBlock returns the indexes of the first begin and end markers
+// in lines.
+func findMarkedBlock(lines []string) (begin, end int, ok bool) {
+	begin = -1
+	for i, l := range lines {
+		switch strings.TrimSpace(l) {
+		case profileBeginMarker:
+			if begin < 0 {
+				begin = i
+			}
+		case profileEndMarker:
+			if begin >= 0 {
+				return begin, i, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// printEdits prints the diffs of the edits that change files.
+func printEdits(edits []fileEdit) {
+	changed := false
+	for _, e := range edits {
+		if e.changed() {
+			fmt.Print(e.diff())
+			changed = true
+		}
+	}
+
+	if !changed {
+		fmt.Println("No files would be changed")
+	}
+}
+
+// applyEdits makes the edits that change files.
+func applyEdits(edits []fileEdit) error {
+	for _, e := range edits {
+		if err := e.apply(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
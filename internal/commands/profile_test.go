@@ -0,0 +1,105 @@
+package commands
+
+import "testing"
+
+const (
+	testSourceLine = `source "/home/u/.go/env"`
+	testLegacyLine = `source "$HOME/.go/env"`
+)
+
+func TestAddMarkedLine(t *testing.T) {
+	block := profileBeginMarker + "\n" + testSourceLine + "\n" + profileEndMarker + "\n"
+
+	for _, tt := range []struct {
+		name    string
+		content string
+		legacy  []string
+		want    string
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			want:    block,
+		},
+		{
+			name:    "append",
+			content: "export A=1\n",
+			want:    "export A=1\n\n" + block,
+		},
+		{
+			name:    "wrap unmarked line",
+			content: "export A=1\n" + testSourceLine + "\nexport B=2\n",
+			want:    "export A=1\n" + block + "export B=2\n",
+		},
+		{
+			name:    "wrap legacy line",
+			content: "export A=1\n" + testLegacyLine + "\nexport B=2\n",
+			legacy:  []string{testLegacyLine},
+			want:    "export A=1\n" + block + "export B=2\n",
+		},
+		{
+			name:    "wrap legacy line of another shell",
+			content: `. "$HOME/.go/env.ps1"` + "\n",
+			legacy:  []string{`. "$HOME/.go/env.ps1"`},
+			want:    block,
+		},
+		{
+			name:    "replace block",
+			content: "export A=1\n\n" + profileBeginMarker + "\nsource \"/old/env\"\n" + profileEndMarker + "\nexport B=2\n",
+			want:    "export A=1\n\n" + block + "export B=2\n",
+		},
+		{
+			name:    "keep block",
+			content: "export A=1\n\n" + block,
+			want:    "export A=1\n\n" + block,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addMarkedLine(tt.content, testSourceLine, tt.legacy...); got != tt.want {
+				t.Errorf("addMarkedLine() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveMarkedLines(t *testing.T) {
+	block := profileBeginMarker + "\n" + testSourceLine + "\n" + profileEndMarker + "\n"
+
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "block with blank line before it",
+			content: "export A=1\n\n" + block,
+			want:    "export A=1\n",
+		},
+		{
+			name:    "block in the middle",
+			content: "export A=1\n\n" + block + "export B=2\n",
+			want:    "export A=1\nexport B=2\n",
+		},
+		{
+			name:    "only block",
+			content: block,
+			want:    "",
+		},
+		{
+			name:    "legacy lines",
+			content: "export A=1\n" + testLegacyLine + "\n" + testSourceLine + "\n",
+			want:    "export A=1\n",
+		},
+		{
+			name:    "nothing of goup",
+			content: "export A=1\n\nexport B=2\n",
+			want:    "export A=1\n\nexport B=2\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeMarkedLines(tt.content, testLegacyLine, testSourceLine); got != tt.want {
+				t.Errorf("removeMarkedLines() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
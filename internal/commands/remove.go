@@ -3,13 +3,16 @@ package commands
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var removeCmdSharedFlag bool
+
 func removeCmd() *cobra.Command {
-	return &cobra.Command{
+	removeCmd := &cobra.Command{
 		Use:     "remove <VERSION>...",
 		Aliases: []string{"rm"},
 		Short:   "Remove Go with a version",
@@ -17,9 +20,15 @@ func removeCmd() *cobra.Command {
 		Example: `
   goup remove 1.15.2
   goup remove 1.16.1 1.16.2
+  goup remove tip@release-branch.go1.22
+  goup remove --shared 1.21.0
 `,
 		RunE: runRemove,
 	}
+
+	removeCmd.PersistentFlags().BoolVar(&removeCmdSharedFlag, "shared", false, "remove the version from the shared store of all users")
+
+	return removeCmd
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
@@ -27,15 +36,40 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("No version is specified")
 	}
 
+	if removeCmdSharedFlag {
+		if err := useShared(); err != nil {
+			return err
+		}
+	}
+
 	for _, ver := range args {
 		logger.Printf("Removing %s", ver)
 
-		if !strings.HasPrefix(ver, "go") {
-			ver = "go" + ver
+		ver = versionName(ver)
+		dir := goupVersionDir(ver)
+		if storeOf(dir) == storeShared && !removeCmdSharedFlag {
+			return fmt.Errorf("%s is in the shared store %s of all users, remove it with --shared", ver, filepath.Dir(dir))
+		}
+
+		if isTipWorktree(dir) {
+			if err := removeTipWorktree(dir); err != nil {
+				return err
+			}
+			continue
+		}
 
+		if ver == tipVersion {
+			// The other tip builds share the git repository of tip.
+			worktrees, err := listTipWorktrees()
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if len(worktrees) > 0 {
+				return fmt.Errorf("tip is shared by %d other tip builds, remove them first: %s", len(worktrees), strings.Join(worktrees, ", "))
+			}
 		}
 
-		if err := os.RemoveAll(GoupDir(ver)); err != nil {
+		if err := os.RemoveAll(dir); err != nil {
 			return err
 		}
 	}
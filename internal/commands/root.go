@@ -3,16 +3,21 @@ package commands
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	homedir string
-	logger  *logrus.Logger
+	homedir  string
+	goupHome string
+	logger   *logrus.Logger
 
 	ProfileFiles []string
+	// ProfileFileSourceContent is the line in ProfileFiles that sources
+	// the env file.
+	ProfileFileSourceContent string
 
 	rootCmdVerboseFlag bool
 )
@@ -30,7 +35,35 @@ func init() {
 		filepath.Join(homedir, ".profile"),
 		filepath.Join(homedir, ".zprofile"),
 		filepath.Join(homedir, ".bash_profile"),
+		filepath.Join(homedir, ".bashrc"),
 	}
+
+	goupHome = findGoupHome()
+	ProfileFileSourceContent = shellEnvs()[0].SourceLine
+}
+
+// findGoupHome returns the directory goup and Go are installed in. It is
+// GOUP_HOME if set, $HOME/.go if goup is set up there, then
+// $XDG_DATA_HOME/goup if XDG_DATA_HOME is set, and $HOME/.go otherwise.
+func findGoupHome() string {
+	if dir := os.Getenv("GOUP_HOME"); dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+	}
+
+	legacy := filepath.Join(homedir, ".go")
+	for _, f := range []string{"env", "current"} {
+		if _, err := os.Lstat(filepath.Join(legacy, f)); err == nil {
+			return legacy
+		}
+	}
+
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "goup")
+	}
+
+	return legacy
 }
 
 func NewCommand() *cobra.Command {
@@ -46,8 +79,22 @@ func NewCommand() *cobra.Command {
 	rootCmd.AddCommand(setCmd())
 	rootCmd.AddCommand(removeCmd())
 	rootCmd.AddCommand(initCmd())
+	rootCmd.AddCommand(deinitCmd())
 	rootCmd.AddCommand(listCmd())
 	rootCmd.AddCommand(searchCmd())
+	rootCmd.AddCommand(linkCmd())
+	rootCmd.AddCommand(unlinkCmd())
+	rootCmd.AddCommand(adoptCmd())
+	rootCmd.AddCommand(migrateCmd())
+	rootCmd.AddCommand(execCmd())
+	rootCmd.AddCommand(envCmd())
+	rootCmd.AddCommand(hookCmd())
+	rootCmd.AddCommand(whichCmd())
+	rootCmd.AddCommand(homeCmd())
+	rootCmd.AddCommand(bisectCmd())
+	rootCmd.AddCommand(cacheCmd())
+	rootCmd.AddCommand(verifyCmd())
+	rootCmd.AddCommand(doctorCmd())
 	rootCmd.AddCommand(versionCmd())
 
 	return rootCmd
@@ -69,12 +116,43 @@ func GoupCurrentBinDir() string {
 	return GoupDir("current", "bin")
 }
 
+// versionName returns the version directory name of ver as given on the
+// command line, e.g. go1.15.2 for 1.15.2 or gotip-go1.23rc1 for
+// tip@go1.23rc1.
+func versionName(ver string) string {
+	if b, ok := parseTipBuild(ver); ok {
+		return b.versionName()
+	}
+	if !strings.HasPrefix(ver, "go") {
+		ver = "go" + ver
+	}
+	return ver
+}
+
+// goupVersionDir returns the directory of ver. Versions in the goup home
+// shadow those of the same name in the shared store, and new versions are
+// installed in the goup home unless useSharedStore is set. Builds of tip
+// are only looked up in the shared store if useSharedStore is set.
 func goupVersionDir(ver string) string {
+	if useSharedStore {
+		return filepath.Join(versionsDir(), ver)
+	}
+	if isMutableVersion(ver) {
+		return GoupDir(ver)
+	}
+
+	for _, s := range versionStores() {
+		dir := filepath.Join(s.Dir, ver)
+		if _, err := os.Lstat(dir); err == nil {
+			return dir
+		}
+	}
+
 	return GoupDir(ver)
 }
 
 func GoupDir(paths ...string) string {
-	elem := []string{homedir, ".go"}
+	elem := []string{goupHome}
 	elem = append(elem, paths...)
 
 	return filepath.Join(elem...)
@@ -0,0 +1,14 @@
+package commands
+
+import "testing"
+
+// setTestGoupHome makes a temporary directory the goup home for the test.
+func setTestGoupHome(t *testing.T) string {
+	t.Helper()
+
+	old := goupHome
+	goupHome = t.TempDir()
+	t.Cleanup(func() { goupHome = old })
+
+	return goupHome
+}
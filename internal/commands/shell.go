@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// The env files set GOUP_HOME, so that goup finds its home in the shell
+// wherever it is, and add goup's and Go's bin directories to PATH once.
+// {{ .Home }} is the expression of GoupDir in the shell, see homeExpr.
+const (
+	shEnvFileContent = `#!/bin/sh
+# goup shell setup
+export GOUP_HOME={{ .Home }}
+case ":${PATH}:" in
+    *:"$GOUP_HOME/bin":*)
+        ;;
+    *)
+        export PATH="$GOUP_HOME/bin:$GOUP_HOME/current/bin:$PATH"
+        ;;
+esac
+`
+	fishEnvFileContent = `# goup shell setup
+set -gx GOUP_HOME {{ .Home }}
+for dir in "$GOUP_HOME/current/bin" "$GOUP_HOME/bin"
+    if not contains -- $dir $PATH
+        set -gx PATH $dir $PATH
+    end
+end
+`
+	nuEnvFileContent = `# goup shell setup
+$env.GOUP_HOME = {{ .Home }}
+let goup_paths = [($env.GOUP_HOME | path join "bin") ($env.GOUP_HOME | path join "current" "bin")]
+$env.PATH = ($env.PATH | split row (char esep) | where {|p| $p not-in $goup_paths } | prepend $goup_paths)
+`
+	pwshEnvFileContent = `# goup shell setup
+$env:GOUP_HOME = {{ .Home }}
+$goupPaths = @([IO.Path]::Combine($env:GOUP_HOME, 'bin'), [IO.Path]::Combine($env:GOUP_HOME, 'current', 'bin'))
+$env:PATH = (@($goupPaths) + @($env:PATH -split [IO.Path]::PathSeparator | Where-Object { $_ -and $goupPaths -notcontains $_ })) -join [IO.Path]::PathSeparator
+Remove-Variable goupPaths
+`
+	elvishEnvFileContent = `# goup shell setup
+set-env GOUP_HOME {{ .Home }}
+var goup-paths = [$E:GOUP_HOME'/bin' $E:GOUP_HOME'/current/bin']
+set paths = [$@goup-paths (each {|p| if (not (has-value $goup-paths $p)) { put $p } } $paths)]
+`
+)
+
+// shellEnv is the setup of a shell for goup: an env file in GoupDir that
+// adds goup's and Go's bin directories to PATH, and the startup files of
+// the shell that source it.
+type shellEnv struct {
+	Name string
+	// Commands are the executables of the shell, it is installed if any
+	// of them is in PATH. POSIX shells are always set up.
+	Commands []string
+	// EnvFile is the name of the env file in GoupDir.
+	EnvFile    string
+	EnvContent string
+	// StartupFiles are the files SourceLine is appended to.
+	StartupFiles []string
+	SourceLine   string
+	// LegacySourceLines are the lines sourcing the env file that older
+	// versions of goup wrote without markers.
+	LegacySourceLines []string
+}
+
+func shellEnvs() []shellEnv {
+	configDir := xdgConfigDir()
+
+	return []shellEnv{
+		{
+			Name:              "sh",
+			EnvFile:           "env",
+			EnvContent:        shEnvFileContent,
+			StartupFiles:      ProfileFiles,
+			SourceLine:        "source " + homeExpr("sh", "env"),
+			LegacySourceLines: []string{`source "$HOME/.go/env"`},
+		},
+		{
+			Name:              "fish",
+			Commands:          []string{"fish"},
+			EnvFile:           "env.fish",
+			EnvContent:        fishEnvFileContent,
+			StartupFiles:      []string{filepath.Join(configDir, "fish", "conf.d", "goup.fish")},
+			SourceLine:        "source " + homeExpr("fish", "env.fish"),
+			LegacySourceLines: []string{`source "$HOME/.go/env.fish"`},
+		},
+		{
+			Name:         "nu",
+			Commands:     []string{"nu"},
+			EnvFile:      "env.nu",
+			EnvContent:   nuEnvFileContent,
+			StartupFiles: []string{filepath.Join(nuConfigDir(), "env.nu")},
+			// source only takes a path that is known when parsing.
+			SourceLine:        fmt.Sprintf("source %q", GoupDir("env.nu")),
+			LegacySourceLines: []string{fmt.Sprintf("source %q", filepath.Join(homedir, ".go", "env.nu"))},
+		},
+		{
+			Name:              "pwsh",
+			Commands:          []string{"pwsh", "powershell"},
+			EnvFile:           "env.ps1",
+			EnvContent:        pwshEnvFileContent,
+			StartupFiles:      pwshProfiles(),
+			SourceLine:        ". " + homeExpr("pwsh", "env.ps1"),
+			LegacySourceLines: []string{`. "$HOME/.go/env.ps1"`},
+		},
+		{
+			Name:              "elvish",
+			Commands:          []string{"elvish"},
+			EnvFile:           "env.elv",
+			EnvContent:        elvishEnvFileContent,
+			StartupFiles:      []string{filepath.Join(elvishConfigDir(), "rc.elv")},
+			SourceLine:        "eval (slurp < " + homeExpr("elvish", "env.elv") + ")",
+			LegacySourceLines: []string{`eval (slurp < ~/.go/env.elv)`},
+		},
+	}
+}
+
+// homeExpr returns the expression of the file elem in GoupDir in the shell
+// family name. It is relative to the home directory of the user if GoupDir
+// is below it, e.g. "$HOME/.go/env", so that startup files can be shared
+// between machines.
+func homeExpr(name string, elem ...string) string {
+	dir := GoupDir(elem...)
+
+	var parts []string
+	if rel, err := filepath.Rel(homedir, dir); err == nil && filepath.IsLocal(rel) {
+		parts = strings.Split(rel, string(filepath.Separator))
+	}
+
+	switch name {
+	case "nu":
+		if parts == nil {
+			return strconv.Quote(dir)
+		}
+		return "($nu.home-path | path join " + strconv.Quote(path.Join(parts...)) + ")"
+	case "pwsh":
+		if parts == nil {
+			return pwshQuote(dir)
+		}
+		quoted := []string{"$HOME"}
+		for _, p := range parts {
+			quoted = append(quoted, pwshQuote(p))
+		}
+		return "([IO.Path]::Combine(" + strings.Join(quoted, ", ") + "))"
+	case "elvish":
+		if parts == nil {
+			return pwshQuote(dir)
+		}
+		return "$E:HOME" + pwshQuote("/"+path.Join(parts...))
+	default:
+		if parts == nil {
+			return shQuote(dir)
+		}
+		// Paths below the home directory don't need more quoting in
+		// double quotes in practice.
+		return `"$HOME/` + path.Join(parts...) + `"`
+	}
+}
+
+// detectShellEnvs returns the setup of the shells that are installed.
+func detectShellEnvs() []shellEnv {
+	var envs []shellEnv
+	for _, s := range shellEnvs() {
+		if s.installed() {
+			envs = append(envs, s)
+		}
+	}
+
+	return envs
+}
+
+func (s shellEnv) installed() bool {
+	if len(s.Commands) == 0 {
+		return true
+	}
+
+	for _, c := range s.Commands {
+		if _, err := exec.LookPath(c); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envFileContent returns the content of the env file of the shell.
+func (s shellEnv) envFileContent() (string, error) {
+	tmpl, err := template.New("").Parse(s.EnvContent)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct{ Home string }{homeExpr(s.Name)}); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// writeEnvFile writes the env file of the shell, replacing an existing
+// one.
+func (s shellEnv) writeEnvFile() error {
+	content, err := s.envFileContent()
+	if err != nil {
+		return err
+	}
+
+	ef := GoupDir(s.EnvFile)
+	if err := os.MkdirAll(filepath.Dir(ef), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(ef, []byte(content), 0664)
+}
+
+// edits returns the edits that set up the shell: writing its env file and
+// adding the line sourcing it to its startup files.
+func (s shellEnv) edits() ([]fileEdit, error) {
+	env, err := newFileEdit(GoupDir(s.EnvFile), false)
+	if err != nil {
+		return nil, err
+	}
+	if env.New, err = s.envFileContent(); err != nil {
+		return nil, err
+	}
+
+	edits := []fileEdit{env}
+	for _, f := range s.StartupFiles {
+		e, err := newFileEdit(f, true)
+		if err != nil {
+			return nil, err
+		}
+		e.New = addMarkedLine(e.Old, s.SourceLine, s.LegacySourceLines...)
+		edits = append(edits, e)
+	}
+
+	return edits, nil
+}
+
+// removalEdits returns the edits that undo the setup of the shell,
+// including the legacy source lines. Startup files that are empty
+// afterwards are removed.
+func (s shellEnv) removalEdits() ([]fileEdit, error) {
+	var edits []fileEdit
+	for _, f := range s.StartupFiles {
+		e, err := newFileEdit(f, true)
+		if err != nil {
+			return nil, err
+		}
+		if !e.Exists {
+			continue
+		}
+		e.New = removeMarkedLines(e.Old, append(s.LegacySourceLines, s.SourceLine)...)
+		e.Remove = e.New != e.Old && strings.TrimSpace(e.New) == ""
+		edits = append(edits, e)
+	}
+
+	env, err := newFileEdit(GoupDir(s.EnvFile), false)
+	if err != nil {
+		return nil, err
+	}
+	env.Remove = true
+
+	return append(edits, env), nil
+}
+
+// xdgConfigDir returns the base directory of user configuration files
+// following the XDG Base Directory Specification, which fish, elvish and
+// PowerShell use on all Unix systems.
+func xdgConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return filepath.Join(homedir, ".config")
+}
+
+func nuConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "nushell")
+	}
+
+	// The platform's configuration directory, e.g. Application Support on
+	// macOS.
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = xdgConfigDir()
+	}
+
+	return filepath.Join(dir, "nushell")
+}
+
+func elvishConfigDir() string {
+	if runtime.GOOS == "windows" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, "elvish")
+		}
+	}
+
+	return filepath.Join(xdgConfigDir(), "elvish")
+}
+
+func pwshProfiles() []string {
+	if runtime.GOOS == "windows" {
+		// PowerShell and Windows PowerShell have profiles of their own.
+		return []string{
+			filepath.Join(homedir, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1"),
+			filepath.Join(homedir, "Documents", "WindowsPowerShell", "Microsoft.PowerShell_profile.ps1"),
+		}
+	}
+
+	return []string{filepath.Join(xdgConfigDir(), "powershell", "Microsoft.PowerShell_profile.ps1")}
+}
+
+// shellFamily returns the name of the shell in shellEnvs that the shell
+// name, e.g. zsh or pwsh.exe, is set up like.
+func shellFamily(name string) (string, bool) {
+	switch strings.TrimSuffix(strings.ToLower(name), ".exe") {
+	case "sh", "bash", "zsh", "ksh", "dash", "ash":
+		return "sh", true
+	case "fish":
+		return "fish", true
+	case "nu", "nushell":
+		return "nu", true
+	case "pwsh", "powershell":
+		return "pwsh", true
+	case "elvish":
+		return "elvish", true
+	default:
+		return "", false
+	}
+}
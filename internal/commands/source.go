@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/owenthereal/goup/internal/entity"
+	"github.com/owenthereal/goup/internal/service"
+)
+
+// installFromSource downloads the source tarball of release and builds it
+// with the make script and the settings of variant, bootstrapping the build
+// with a Go installed by goup.
+func installFromSource(release entity.Release, variant buildVariant) error {
+	version := variant.versionName(release.Version)
+	targetDir := goupVersionDir(version)
+
+	if checkInstalled(targetDir) {
+		if md, err := readInstallMetadata(targetDir); err == nil && !variant.builtWith(md.Build) {
+			return fmt.Errorf("%s is already installed with other settings, remove it first with `goup remove`", version)
+		}
+		logger.Printf("%s: already installed in %v", version, targetDir)
+		return nil
+	}
+
+	fg, err := release.SourceFile()
+	if err != nil {
+		return err
+	}
+
+	archiveFile, fileUrl, err := downloadArchive(targetDir, fg)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("Unpacking %v ...", archiveFile)
+	files, err := unpackArchive(targetDir, archiveFile)
+	if err != nil {
+		return fmt.Errorf("extracting archive %v: %v", archiveFile, err)
+	}
+
+	// Restore the build output of the same archive and settings from the
+	// build cache instead of building it again.
+	key := buildCacheKey(fg.Filename+" "+strings.TrimSpace(fg.Sha256), nil, variant.Env)
+	var bm *buildMetadata
+	ok, err := restoreBuild(key, targetDir)
+	if err != nil {
+		logger.Debugf("failed to restore the build %s: %v", key, err)
+	}
+	if ok {
+		logger.Printf("Using the cached build %s", key[:12])
+		bm = newBuildMetadata("", variant)
+		bm.Cache = key
+	} else {
+		bootstrap, err := ensureBootstrap(release.Version)
+		if err != nil {
+			return err
+		}
+		if err := buildSource(targetDir, bootstrap, variant.Env); err != nil {
+			return err
+		}
+		bm = newBuildMetadata(bootstrap, variant)
+
+		output, err := sourceBuildOutput(targetDir, manifest{Archive: fg.Filename, Files: files})
+		if err == nil {
+			err = saveBuild(targetDir, output, key)
+		}
+		if err != nil {
+			logger.Warnf("failed to cache the build: %v", err)
+		}
+	}
+
+	m, err := scanManifest(targetDir, fg.Filename)
+	if err != nil {
+		return err
+	}
+	if err := writeManifest(targetDir, m); err != nil {
+		return err
+	}
+
+	md := newInstallMetadata(version)
+	md.Host = GetGoHost()
+	md.URL = fileUrl
+	md.ArchiveSHA256 = fg.Sha256
+	md.Build = bm
+	if err := setInstalled(targetDir, md); err != nil {
+		return err
+	}
+	logger.Printf("Success: %s built from source in %v", version, targetDir)
+	return nil
+}
+
+// buildSource runs the make script of the Go source tree in targetDir with
+// the Go in bootstrap as GOROOT_BOOTSTRAP, adding env to the environment of
+// the build.
+func buildSource(targetDir, bootstrap string, env []string) error {
+	if len(env) > 0 {
+		logger.Printf("Building with %s", strings.Join(env, " "))
+	}
+
+	if bootstrap == "" {
+		logger.Printf("Building %v ...", targetDir)
+		return runMake(targetDir, env)
+	}
+
+	logger.Printf("Building %v using %v ...", targetDir, bootstrap)
+	return runMake(targetDir, append([]string{"GOROOT_BOOTSTRAP=" + bootstrap}, env...))
+}
+
+// ensureBootstrap returns the GOROOT of a Go installed by goup that can
+// bootstrap building version from source, or "" if version does not need a
+// bootstrap toolchain. If no suitable Go is installed, the oldest suitable
+// release is installed first, building it from source in turn if it has no
+// binary release for the host platform.
+func ensureBootstrap(version string) (string, error) {
+	min := entity.MinimumBootstrapVersion(version)
+	if min == "" {
+		return "", nil
+	}
+
+	goroot, ok, err := findBootstrap(version, min)
+	if err != nil || ok {
+		return goroot, err
+	}
+
+	release, err := bootstrapRelease(version, min)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Printf("Installing %s to bootstrap %s ...", release.Version, version)
+	if _, err := release.ArchiveFile(); err == nil {
+		err = install(release, entity.HostPlatform())
+	} else {
+		err = installFromSource(release, buildVariant{})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s to bootstrap %s: %v", release.Version, version, err)
+	}
+
+	return goupVersionDir(release.Version), nil
+}
+
+// findBootstrap returns the GOROOT of a Go release installed by goup for
+// the host platform that is at least min. Releases older than version are
+// preferred, newest first.
+func findBootstrap(version, min string) (string, bool, error) {
+	vers, err := listGoVers()
+	if err != nil && !os.IsNotExist(err) {
+		return "", false, err
+	}
+
+	var older, newer goVer
+	for _, v := range vers {
+		name := "go" + v.Ver
+		if name == version || !entity.ValidVersion(name) || entity.CompareVersions(name, min) < 0 {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(v.Dir, "bin", goExe())); err != nil {
+			continue
+		}
+
+		best := &newer
+		if entity.CompareVersions(name, version) < 0 {
+			best = &older
+		}
+		if best.Ver == "" || entity.CompareVersions(v.Ver, best.Ver) > 0 {
+			*best = v
+		}
+	}
+
+	switch {
+	case older.Ver != "":
+		return older.Dir, true, nil
+	case newer.Ver != "":
+		return newer.Dir, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// bootstrapRelease returns the newest stable release in the minor version
+// of min that can bootstrap version.
+func bootstrapRelease(version, min string) (entity.Release, error) {
+	min = entity.BootstrapReleaseMinimum(version, min)
+
+	svc := service.NewGoReleaseService(GetGoHost())
+	rl, err := svc.GetReleaseList("all")
+	if err != nil {
+		return entity.Release{}, err
+	}
+
+	var best entity.Release
+	for _, r := range rl {
+		if !r.Stable || entity.MinorVersion(r.Version) != entity.MinorVersion(min) {
+			continue
+		}
+		if entity.CompareVersions(r.Version, min) < 0 || entity.CompareVersions(r.Version, version) >= 0 {
+			continue
+		}
+		if best.Version == "" || entity.CompareVersions(r.Version, best.Version) > 0 {
+			best = r
+		}
+	}
+
+	if best.Version == "" {
+		return best, fmt.Errorf("no release found to bootstrap %s, which needs %s or later", version, min)
+	}
+
+	return best, nil
+}
+
+var goversionRe = regexp.MustCompile(`(?m)^const Version = (\d+)$`)
+
+// sourceTreeVersion returns the Go version, e.g. go1.26, that the source
+// tree in root is developing.
+func sourceTreeVersion(root string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(root, "src", "internal", "goversion", "goversion.go"))
+	if err != nil {
+		return "", err
+	}
+
+	m := goversionRe.FindSubmatch(b)
+	if m == nil {
+		return "", errors.New("failed to find the Go version in internal/goversion")
+	}
+
+	return "go1." + string(m[1]), nil
+}
+
+func goExe() string {
+	if runtime.GOOS == "windows" {
+		return "go.exe"
+	}
+	return "go"
+}
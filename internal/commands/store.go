@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/owenthereal/goup/internal/git"
+)
+
+// defaultSharedStore is the shared store used if it exists and
+// GOUP_SHARED_STORE is not set.
+const defaultSharedStore = "/opt/goup"
+
+// Where a version directory lives.
+const (
+	storeUser   = "user"
+	storeShared = "shared"
+)
+
+// useSharedStore makes new versions go to the shared store, set by
+// install --shared and remove --shared.
+var useSharedStore bool
+
+// sharedStoreDir returns the directory of the Go versions shared by all
+// users of the machine, or "" if there is none. It is GOUP_SHARED_STORE if
+// set, and /opt/goup if it exists.
+func sharedStoreDir() string {
+	if dir := os.Getenv("GOUP_SHARED_STORE"); dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	if fi, err := os.Stat(defaultSharedStore); err == nil && fi.IsDir() {
+		return defaultSharedStore
+	}
+
+	return ""
+}
+
+// versionsDir returns the directory new versions are installed in, the
+// shared store if useSharedStore is set and the goup home otherwise.
+func versionsDir() string {
+	if useSharedStore {
+		return sharedStoreDir()
+	}
+	return GoupDir()
+}
+
+// isMutableVersion reports whether the version directory name is of a build
+// of tip, which goup install tip updates in place. Those in the shared store
+// are only used with --shared, so that users don't rebuild them for
+// everybody by accident.
+func isMutableVersion(name string) bool {
+	return name == tipVersion || strings.HasPrefix(name, tipVersion+"-")
+}
+
+// versionStore is a directory Go versions are installed in.
+type versionStore struct {
+	Name string
+	Dir  string
+}
+
+// versionStores returns the stores versions are looked up in, the goup
+// home first.
+func versionStores() []versionStore {
+	stores := []versionStore{{Name: storeUser, Dir: GoupDir()}}
+	if shared := sharedStoreDir(); shared != "" && shared != GoupDir() {
+		stores = append(stores, versionStore{Name: storeShared, Dir: shared})
+	}
+
+	return stores
+}
+
+// storeOf returns the name of the store dir is in.
+func storeOf(dir string) string {
+	if shared := sharedStoreDir(); shared != "" && isSubdir(shared, dir) && !isSubdir(GoupDir(), dir) {
+		return storeShared
+	}
+	return storeUser
+}
+
+// useShared makes new versions go to the shared store, creating it if
+// needed.
+func useShared() error {
+	dir := sharedStoreDir()
+	if dir == "" {
+		return fmt.Errorf("there is no shared store, set GOUP_SHARED_STORE or create %s", defaultSharedStore)
+	}
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return fmt.Errorf("failed to create the shared store %s: %v", dir, err)
+	}
+	// mkdir applies the umask and ignores the setgid bit, which makes
+	// the versions installed by other members inherit the group.
+	if err := shareStoreDir(dir); err != nil {
+		return fmt.Errorf("failed to make the shared store %s writable by its group: %v", dir, err)
+	}
+
+	useSharedStore = true
+	return nil
+}
+
+// shareStoreDir makes the store dir writable by its group, with the setgid
+// bit set. It is left alone if it is already, since only its owner may
+// change it.
+func shareStoreDir(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	const mode = 0775 | fs.ModeSetgid
+	if fi.Mode()&mode == mode {
+		return nil
+	}
+	return os.Chmod(dir, fi.Mode().Perm()|mode)
+}
+
+// shareVersion makes the directory of ver in the shared store usable by
+// everybody, together with the main clone of tip for the tip worktrees.
+func shareVersion(ver string) error {
+	dirs := []string{goupVersionDir(ver)}
+	if isTipWorktree(dirs[0]) {
+		dirs = append(dirs, goupVersionDir(tipVersion))
+	}
+
+	if tip := goupVersionDir(tipVersion); slices.Contains(dirs, tip) && git.Installed() {
+		// Keep the objects git adds later writable by the group too.
+		repo := tipRepo{dir: tip}
+		if err := repo.git("config", "core.sharedRepository", "group"); err != nil {
+			logger.Warnf("failed to share the git repository of tip: %v", err)
+		}
+	}
+
+	for _, dir := range dirs {
+		if err := shareDir(dir); err != nil {
+			return fmt.Errorf("failed to share %s: %v", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// shareDir makes dir and everything below it writable by the group and
+// readable by everybody, so that the members of the group of the shared
+// store can manage its versions and all users can use them. Directories
+// get the setgid bit, so that new files inherit their group.
+func shareDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := fi.Mode().Perm() | 0664
+		if d.IsDir() || fi.Mode().Perm()&0111 != 0 {
+			mode |= 0775
+		}
+		if d.IsDir() {
+			mode |= fs.ModeSetgid
+		}
+		return os.Chmod(path, mode)
+	})
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setTestSharedStore makes a temporary directory the shared store for the
+// test.
+func setTestSharedStore(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("GOUP_SHARED_STORE", dir)
+	old := useSharedStore
+	useSharedStore = false
+	t.Cleanup(func() { useSharedStore = old })
+
+	return dir
+}
+
+// writeTestVersion creates an installed version named ver in the store
+// dir.
+func writeTestVersion(t *testing.T, dir, ver string) string {
+	t.Helper()
+
+	goroot := filepath.Join(dir, ver)
+	if err := os.MkdirAll(filepath.Join(goroot, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goroot, "bin", "go"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeInstallMetadata(goroot, newInstallMetadata(ver)); err != nil {
+		t.Fatal(err)
+	}
+
+	return goroot
+}
+
+func TestShareDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []struct {
+		name string
+		perm fs.FileMode
+	}{
+		{"VERSION", 0600},
+		{"bin/go", 0700},
+		{"src/fmt/print.go", 0644},
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, f.perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("VERSION", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := shareDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]fs.FileMode{
+		".":                0775 | fs.ModeDir | fs.ModeSetgid,
+		"bin":              0775 | fs.ModeDir | fs.ModeSetgid,
+		"src/fmt":          0775 | fs.ModeDir | fs.ModeSetgid,
+		"VERSION":          0664,
+		"bin/go":           0775,
+		"src/fmt/print.go": 0664,
+	} {
+		fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode() & (fs.ModeDir | fs.ModeSetgid | fs.ModePerm); got != want {
+			t.Errorf("mode of %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestListGoVersShadowing(t *testing.T) {
+	home := setTestGoupHome(t)
+	shared := setTestSharedStore(t)
+
+	writeTestVersion(t, home, "go1.22.0")
+	writeTestVersion(t, shared, "go1.22.0")
+	writeTestVersion(t, shared, "go1.21.0")
+	writeTestVersion(t, shared, tipVersion)
+
+	vers, err := listGoVers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []goVer{
+		{Ver: "1.21.0", Dir: filepath.Join(shared, "go1.21.0"), Store: storeShared},
+		{Ver: "1.22.0", Dir: filepath.Join(home, "go1.22.0"), Store: storeUser},
+	}
+	if len(vers) != len(want) {
+		t.Fatalf("listGoVers() = %+v, want %+v", vers, want)
+	}
+	for i := range want {
+		if vers[i] != want[i] {
+			t.Errorf("listGoVers()[%d] = %+v, want %+v", i, vers[i], want[i])
+		}
+	}
+}
+
+func TestGoupVersionDir(t *testing.T) {
+	home := setTestGoupHome(t)
+	shared := setTestSharedStore(t)
+
+	writeTestVersion(t, home, "go1.22.0")
+	writeTestVersion(t, shared, "go1.22.0")
+	writeTestVersion(t, shared, "go1.21.0")
+	writeTestVersion(t, shared, tipVersion)
+	writeTestVersion(t, shared, tipVersion+"-master")
+
+	for _, tt := range []struct {
+		ver    string
+		shared bool
+		want   string
+	}{
+		{"go1.22.0", false, filepath.Join(home, "go1.22.0")},
+		{"go1.21.0", false, filepath.Join(shared, "go1.21.0")},
+		{"go1.20.0", false, filepath.Join(home, "go1.20.0")},
+		// Builds of tip in the shared store are only used with --shared.
+		{tipVersion, false, filepath.Join(home, tipVersion)},
+		{tipVersion + "-master", false, filepath.Join(home, tipVersion+"-master")},
+		{tipVersion, true, filepath.Join(shared, tipVersion)},
+		{"go1.22.0", true, filepath.Join(shared, "go1.22.0")},
+	} {
+		useSharedStore = tt.shared
+		if got := goupVersionDir(tt.ver); got != tt.want {
+			t.Errorf("goupVersionDir(%q) with useSharedStore %v = %s, want %s", tt.ver, tt.shared, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package commands
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestUseShared(t *testing.T) {
+	setTestSharedStore(t)
+	dir := filepath.Join(t.TempDir(), "opt", "goup")
+	t.Setenv("GOUP_SHARED_STORE", dir)
+
+	// A umask like 022 must not keep the store from being group-writable.
+	old := syscall.Umask(022)
+	t.Cleanup(func() { syscall.Umask(old) })
+
+	if err := useShared(); err != nil {
+		t.Fatal(err)
+	}
+	if !useSharedStore {
+		t.Error("useShared() didn't select the shared store")
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 0775 | fs.ModeDir | fs.ModeSetgid; fi.Mode()&(fs.ModeDir|fs.ModeSetgid|fs.ModePerm) != want {
+		t.Errorf("mode of the shared store = %v, want %v", fi.Mode(), want)
+	}
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// Test suites that can be run after building tip, see the --test flag.
+const (
+	testFull  = "full"
+	testShort = "short"
+)
+
+// testLogFile is the output of the last test run of a Go source tree.
+const testLogFile = ".goup-test.log"
+
+func checkTestSuite(suite string) error {
+	switch suite {
+	case "", testFull, testShort:
+		return nil
+	default:
+		return fmt.Errorf("invalid test suite %q, must be %s or %s", suite, testFull, testShort)
+	}
+}
+
+// runTestSuite runs the tests of the Go built in root, adding env to the
+// environment of the tests. The full suite is the run script, the short
+// suite is go test -short std cmd. Failing tests are reported in the
+// result, not as an error.
+func runTestSuite(root, suite string, env []string) (*testMetadata, error) {
+	var cmd *exec.Cmd
+	switch suite {
+	case testFull:
+		cmd = exec.Command(filepath.Join(root, "src", runScript()))
+	case testShort:
+		cmd = exec.Command(filepath.Join(root, "bin", goExe()), "test", "-short", "std", "cmd")
+	default:
+		return nil, checkTestSuite(suite)
+	}
+
+	cmd.Dir = filepath.Join(root, "src")
+	cmd.Env = append(os.Environ(),
+		"GOROOT="+root,
+		"PATH="+filepath.Join(root, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+	cmd.Env = append(cmd.Env, env...)
+
+	logFile := filepath.Join(root, testLogFile)
+	logger.Printf("Running the %s test suite of %v ...", suite, root)
+
+	start := time.Now()
+	err := runLogged(cmd, logFile)
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run tests: %v", err)
+	}
+
+	tm := &testMetadata{
+		Suite:    suite,
+		Passed:   err == nil,
+		Duration: time.Since(start).Round(time.Second).String(),
+		Log:      logFile,
+		RanAt:    start.UTC(),
+	}
+	if tm.Passed {
+		logger.Printf("Tests passed in %s", tm.Duration)
+	} else {
+		logger.Errorf("Tests failed in %s, the full test log is at %s", tm.Duration, logFile)
+	}
+
+	return tm, nil
+}
+
+func runScript() string {
+	switch runtime.GOOS {
+	case "plan9":
+		return "run.rc"
+	case "windows":
+		return "run.bat"
+	default:
+		return "run.bash"
+	}
+}
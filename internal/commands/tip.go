@@ -0,0 +1,623 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/owenthereal/goup/internal/git"
+
+	"github.com/manifoldco/promptui"
+)
+
+// tipVersion is the version directory of the Go development tree. It is a
+// full git clone; other tip builds are git worktrees of it.
+const tipVersion = "gotip"
+
+// Policies for untracked files in a tip checkout, see the --clean flag.
+const (
+	cleanAuto  = "auto"
+	cleanNone  = "none"
+	cleanForce = "force"
+)
+
+// goupFilesPattern matches the files goup keeps in a version directory, which
+// are preserved when cleaning untracked files.
+const goupFilesPattern = "/.goup-*"
+
+func checkCleanPolicy(policy string) error {
+	switch policy {
+	case cleanAuto, cleanNone, cleanForce:
+		return nil
+	default:
+		return fmt.Errorf("invalid clean policy %q, must be one of %s, %s or %s", policy, cleanAuto, cleanNone, cleanForce)
+	}
+}
+
+// tipBuild selects what installTip checks out and builds.
+type tipBuild struct {
+	// Ref is the branch, tag or commit to build instead of master.
+	Ref string
+	// CL is the Gerrit change list number to build instead of master.
+	CL string
+	// Patches are patch files applied on top of the checkout.
+	Patches []string
+	// Variant are the settings the checkout is built with.
+	Variant buildVariant
+}
+
+// parseTipBuild parses tip versions of the form tip or tip@REF.
+func parseTipBuild(ver string) (tipBuild, bool) {
+	ver = strings.TrimPrefix(ver, "go")
+	if ver == "tip" {
+		return tipBuild{}, true
+	}
+	if ref := strings.TrimPrefix(ver, "tip@"); ref != ver && ref != "" {
+		return tipBuild{Ref: ref}, true
+	}
+	return tipBuild{}, false
+}
+
+func isTipVersion(ver string) bool {
+	_, ok := parseTipBuild(ver)
+	return ok
+}
+
+var unsafeRefChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// versionName returns the name of the version directory the build is
+// installed in, e.g. gotip-release-branch.go1.22 for a branch.
+func (b tipBuild) versionName() string {
+	if b.Ref == "" {
+		return tipVersion
+	}
+
+	ref := b.Ref
+	if isCommitHash(ref) && len(ref) > 12 {
+		ref = ref[:12]
+	}
+	return tipVersion + "-" + unsafeRefChars.ReplaceAllString(ref, "-")
+}
+
+var commitHashRe = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func isCommitHash(ref string) bool {
+	return commitHashRe.MatchString(ref)
+}
+
+// tipRepo runs git in a checkout of the Go development tree.
+type tipRepo struct {
+	dir string
+}
+
+// vcs returns the git implementation for the checkout, which is the git
+// command if it is installed and a pure Go implementation otherwise. Unless
+// goup runs interactively, git doesn't prompt for credentials.
+func (r tipRepo) vcs() git.Repo {
+	opts := git.Options{Stdout: os.Stdout, Stderr: os.Stderr}
+	if !interactive() {
+		opts.Env = []string{"GIT_TERMINAL_PROMPT=0"}
+	}
+	return git.Open(r.dir, opts)
+}
+
+// git runs git, which never reads from stdin. Unless goup runs
+// interactively, git doesn't prompt for credentials either.
+func (r tipRepo) git(args ...string) error {
+	cmd := r.command(args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (r tipRepo) gitOutput(args ...string) ([]byte, error) {
+	return r.command(args...).Output()
+}
+
+// gitInteractive runs git with the user's stdin, e.g. for git clean -i.
+func (r tipRepo) gitInteractive(args ...string) error {
+	cmd := r.command(args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (r tipRepo) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	if !interactive() {
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	}
+	return cmd
+}
+
+// clean removes build artifacts left over from earlier builds, and the
+// untracked files according to policy.
+func (r tipRepo) clean(policy string) error {
+	// It shouldn't be the case, but in practice sometimes binary artifacts
+	// generated by earlier Go versions interfere with the build.
+	//
+	// Untracked files that are not gitignored might be artifacts that used
+	// to be ignored in previous versions, or precious uncommitted source
+	// files.
+	switch policy {
+	case cleanAuto:
+		// Ask the user what to do about them. Without a terminal or the
+		// git command, keep them.
+		if interactive() && git.Installed() {
+			if err := r.gitInteractive("clean", "-i", "-d", "-e", goupFilesPattern); err != nil {
+				return fmt.Errorf("failed to cleanup git repository: %v", err)
+			}
+		}
+	case cleanForce:
+		if err := r.vcs().Clean(goupFilesPattern); err != nil {
+			return fmt.Errorf("failed to cleanup git repository: %v", err)
+		}
+	case cleanNone:
+	default:
+		return checkCleanPolicy(policy)
+	}
+
+	// Wipe away probably boring ignored files without bothering the user.
+	if err := r.vcs().CleanIgnored(); err != nil {
+		return fmt.Errorf("failed to cleanup git repository: %v", err)
+	}
+
+	return nil
+}
+
+var clNumberRe = regexp.MustCompile(`^\d+$`)
+
+// latestPatchSet returns the ref and number of the latest patch set of the
+// Gerrit change list cl.
+func latestPatchSet(repo tipRepo, cl string) (string, int, error) {
+	// CL is for googlesource, ls-remote against upstream
+	// ls-remote outputs a number of refs like:
+	// 2621ba2c60d05ec0b9ef37cd71e45047b004cead	refs/changes/37/227037/1
+	// 51f2af2be0878e1541d2769bd9d977a7e99db9ab	refs/changes/37/227037/2
+	// af1f3b008281c61c54a5d203ffb69334b7af007c	refs/changes/37/227037/3
+	// 6a10ebae05ce4b01cb93b73c47bef67c0f5c5f2a	refs/changes/37/227037/meta
+	refs, err := repo.vcs().ListRemote("upstream")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to list remotes: %v", err)
+	}
+	r := regexp.MustCompile(`^refs/changes/\d\d/` + cl + `/(\d+)$`)
+	var ref string
+	var patchSet int
+	for _, rf := range refs {
+		m := r.FindStringSubmatch(rf.Name)
+		if m == nil {
+			continue
+		}
+		ps, _ := strconv.Atoi(m[1])
+		if ps > patchSet {
+			patchSet = ps
+			ref = rf.Name
+		}
+	}
+	if ref == "" {
+		return "", 0, fmt.Errorf("CL %v not found", cl)
+	}
+
+	return ref, patchSet, nil
+}
+
+// readPatches reads the patch files and records their SHA-256.
+func readPatches(files []string) ([]patchMetadata, error) {
+	var patches []patchMetadata
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := fileSHA256(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read patch: %v", err)
+		}
+		patches = append(patches, patchMetadata{File: abs, Sha256: sum})
+	}
+
+	return patches, nil
+}
+
+// patchesID returns a short identifier of the patches, in the order they
+// are applied.
+func patchesID(patches []patchMetadata) string {
+	h := sha256.New()
+	for _, p := range patches {
+		io.WriteString(h, p.Sha256+"\n")
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:8]
+}
+
+// applyPatches applies the patches to the checkout one after another, so
+// that a patch may depend on the ones before it. If one does not apply, the
+// changes of the others are undone.
+func (r tipRepo) applyPatches(patches []patchMetadata) error {
+	for _, p := range patches {
+		logger.Printf("Applying %v...", p.File)
+		// git apply leaves the checkout untouched if the patch does not
+		// apply.
+		if err := r.git("apply", p.File); err != nil {
+			if rerr := r.vcs().Reset(); rerr != nil {
+				logger.Warnf("failed to reset git repository: %v", rerr)
+			}
+			if rerr := r.vcs().Clean(goupFilesPattern); rerr != nil {
+				logger.Warnf("failed to cleanup git repository: %v", rerr)
+			}
+			return fmt.Errorf("%s does not apply to the checkout: %v", p.File, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureTipRepo clones the Go development tree into the tip version
+// directory if it has not been cloned before.
+func ensureTipRepo() (tipRepo, error) {
+	repo := tipRepo{dir: goupVersionDir(tipVersion)}
+	return repo, repo.ensureClone()
+}
+
+// ensureClone makes a shallow clone of the Go development tree in the
+// directory of the checkout if it has not been cloned before.
+func (r tipRepo) ensureClone() error {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create repository: %v", err)
+	}
+
+	if err := r.vcs().Clone(GetGoSourceGitURL(), 1); err != nil {
+		return fmt.Errorf("failed to clone git repository: %v", err)
+	}
+
+	if err := r.vcs().AddRemote("upstream", GetGoSourceUpstreamGitURL()); err != nil {
+		return fmt.Errorf("failed to add upstream git repository: %v", err)
+	}
+
+	return nil
+}
+
+// ensureTipWorktree returns the checkout for the version directory name,
+// adding it as a worktree of the Go development tree if needed so that all
+// tip builds share one git object store. Without the git command, which
+// is needed for worktrees, the checkout is a clone of its own.
+func ensureTipWorktree(name string) (tipRepo, error) {
+	if !git.Installed() {
+		wt := tipRepo{dir: goupVersionDir(name)}
+		return wt, wt.ensureClone()
+	}
+
+	repo, err := ensureTipRepo()
+	if err != nil || name == tipVersion {
+		return repo, err
+	}
+
+	wt := tipRepo{dir: goupVersionDir(name)}
+	if _, err := os.Stat(filepath.Join(wt.dir, ".git")); err == nil {
+		return wt, nil
+	}
+
+	if err := repo.git("worktree", "add", "--detach", wt.dir); err != nil {
+		return wt, fmt.Errorf("failed to add git worktree %s: %v", wt.dir, err)
+	}
+
+	return wt, nil
+}
+
+// isTipWorktree reports whether dir is a worktree of the Go development
+// tree rather than the main clone.
+func isTipWorktree(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil && !fi.IsDir()
+}
+
+// removeTipWorktree removes the worktree in dir from the Go development
+// tree, and the directory itself.
+func removeTipWorktree(dir string) error {
+	repo := tipRepo{dir: goupVersionDir(tipVersion)}
+	if err := repo.git("worktree", "remove", "--force", dir); err != nil {
+		// The main clone may be gone, remove the directory anyway.
+		logger.Debugf("failed to remove git worktree %s: %v", dir, err)
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// listTipWorktrees returns the version directories of the worktrees of the
+// Go development tree, which are next to its main clone.
+func listTipWorktrees() ([]string, error) {
+	baseDir := filepath.Dir(goupVersionDir(tipVersion))
+	files, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, f := range files {
+		dir := filepath.Join(baseDir, f.Name())
+		if f.IsDir() && strings.HasPrefix(f.Name(), tipVersion+"-") && isTipWorktree(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs, nil
+}
+
+// buildTip builds the checkout with the settings of variant, or restores
+// the build output cached under key unless --force is set. Successful
+// builds are added to the build cache.
+func buildTip(repo tipRepo, key string, variant buildVariant) (*buildMetadata, error) {
+	if !installCmdForceFlag {
+		ok, err := restoreBuild(key, repo.dir)
+		if err != nil {
+			logger.Debugf("failed to restore the build %s: %v", key, err)
+		}
+		if ok {
+			logger.Printf("Using the cached build %s", key[:12])
+			bm := newBuildMetadata("", variant)
+			bm.Cache = key
+			return bm, nil
+		}
+	}
+
+	// Always set GOROOT_BOOTSTRAP to a Go installed by goup rather than
+	// relying on a go in PATH. This also works around make.bat not
+	// autodetecting GOROOT_BOOTSTRAP. Issue 28641.
+	devVersion, err := sourceTreeVersion(repo.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect the Go version of tip: %v", err)
+	}
+	bootstrap, err := ensureBootstrap(devVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := buildSource(repo.dir, bootstrap, variant.Env); err != nil {
+		return nil, err
+	}
+
+	files, err := repo.buildOutput()
+	if err == nil {
+		err = saveBuild(repo.dir, files, key)
+	}
+	if err != nil {
+		logger.Warnf("failed to cache the build: %v", err)
+	}
+
+	return newBuildMetadata(bootstrap, variant), nil
+}
+
+// builtTip returns the install metadata of the checkout of repo if it is
+// built at commit with the settings of variant.
+func builtTip(repo tipRepo, commit string, variant buildVariant) (installMetadata, bool) {
+	md, err := readInstallMetadata(repo.dir)
+	if err != nil || md.Git == nil || md.Git.Commit != commit || !variant.builtWith(md.Build) {
+		return md, false
+	}
+	if _, err := os.Stat(filepath.Join(repo.dir, "bin", goExe())); err != nil {
+		return md, false
+	}
+
+	// bin/go may be built from another commit than the one checked out.
+	head, err := repo.vcs().Head()
+	return md, err == nil && head == commit
+}
+
+// invalidateTipBuild clears the commit recorded in the install metadata
+// of dir before another commit is checked out, so that the build is not
+// taken for one of the recorded commit if building the other one fails.
+func invalidateTipBuild(dir string) error {
+	md, err := readInstallMetadata(dir)
+	if err != nil || md.Git == nil || md.Git.Commit == "" {
+		return nil
+	}
+
+	md.Git.Commit = ""
+	return writeInstallMetadata(dir, md)
+}
+
+// installTip fetches, checks out and builds the Go development tree
+// selected by b, returning the name of the version directory it was
+// installed in. Builds other than master are git worktrees of the tip
+// version directory.
+func installTip(b tipBuild) (string, error) {
+	if err := checkCleanPolicy(installCmdCleanFlag); err != nil {
+		return "", err
+	}
+
+	name := b.versionName()
+
+	if len(b.Patches) > 0 && !git.Installed() {
+		return "", errors.New("applying patches requires the git command")
+	}
+	patches, err := readPatches(b.Patches)
+	if err != nil {
+		return "", err
+	}
+
+	// A CL is fetched from upstream at its latest patch set. Each patch set
+	// is built in its own worktree, e.g. gotip-cl227037-ps3.
+	var clRef string
+	var patchSet int
+	if b.CL != "" {
+		if !clNumberRe.MatchString(b.CL) {
+			return "", fmt.Errorf("invalid CL number %q", b.CL)
+		}
+
+		switch {
+		case installCmdYesFlag:
+		case !interactive():
+			return "", fmt.Errorf("building CL %s executes code from go.dev/cl/%s, confirm with --yes", b.CL, b.CL)
+		default:
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("This will download and execute code from go.dev/cl/%s, continue", b.CL),
+				IsConfirm: true,
+			}
+
+			if _, err := prompt.Run(); err != nil {
+				return "", fmt.Errorf("interrupted")
+			}
+		}
+
+		repo, err := ensureTipRepo()
+		if err != nil {
+			return "", err
+		}
+
+		clRef, patchSet, err = latestPatchSet(repo, b.CL)
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-cl%s-ps%d", tipVersion, b.CL, patchSet)
+	}
+
+	// Patched builds are variants named after the patches, e.g.
+	// gotip-patch-1a2b3c4d.
+	if len(patches) > 0 {
+		name += "-patch-" + patchesID(patches)
+	}
+	name = b.Variant.versionName(name)
+
+	// A worktree added for a new variant is removed again if its patches
+	// don't apply.
+	_, statErr := os.Lstat(goupVersionDir(name))
+	newWorktree := os.IsNotExist(statErr) && name != tipVersion
+
+	repo, err := ensureTipWorktree(name)
+	if err != nil {
+		return "", err
+	}
+
+	var commit string
+	switch {
+	case b.CL != "":
+		logger.Printf("Fetching CL %v, Patch Set %v...", b.CL, patchSet)
+		if commit, err = repo.vcs().Fetch("upstream", clRef, 0); err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %v", clRef, err)
+		}
+	case b.Ref != "":
+		// Only fetch the commit itself, the history of another branch
+		// is mostly unrelated to the shallow clone of master.
+		logger.Printf("Fetching %v...", b.Ref)
+		if commit, err = repo.vcs().Fetch("origin", b.Ref, 1); err != nil {
+			if isCommitHash(b.Ref) && len(b.Ref) < 40 {
+				return "", fmt.Errorf("failed to fetch %s, commits can only be fetched by their full hash: %v", b.Ref, err)
+			}
+			return "", fmt.Errorf("failed to fetch %s: %v", b.Ref, err)
+		}
+	default:
+		logger.Printf("Updating the go development tree...")
+		if commit, err = repo.vcs().Fetch("origin", "master", 0); err != nil {
+			return "", fmt.Errorf("failed to fetch git repository updates: %v", err)
+		}
+	}
+
+	// Skip the build if the fetched commit has been built before.
+	if md, ok := builtTip(repo, commit, b.Variant); ok && !installCmdForceFlag {
+		logger.Printf("%s: already built at %s, use --force to rebuild", name, shortCommit(commit))
+		return name, testTip(repo.dir, md, b.Variant.Env)
+	}
+	if md, err := readInstallMetadata(repo.dir); err == nil && md.Git != nil && md.Git.Commit != "" {
+		if md.Git.Commit != commit && git.Installed() {
+			logger.Printf("Changes since the last build at %s:", shortCommit(md.Git.Commit))
+			if err := repo.git("--no-pager", "log", "--oneline", "-n", "50", md.Git.Commit+".."+commit); err != nil {
+				logger.Debugf("failed to show changes since %s: %v", md.Git.Commit, err)
+			}
+		}
+	}
+
+	// A patched variant only has local changes from its patches, which are
+	// applied again after the checkout.
+	if len(patches) > 0 {
+		if err := repo.vcs().Reset(); err != nil {
+			return "", fmt.Errorf("failed to reset git repository: %v", err)
+		}
+		if err := repo.vcs().Clean(goupFilesPattern); err != nil {
+			return "", fmt.Errorf("failed to cleanup git repository: %v", err)
+		}
+	}
+
+	// The build of the recorded commit no longer matches the checkout, even
+	// if building the new one fails.
+	if err := invalidateTipBuild(repo.dir); err != nil {
+		return "", err
+	}
+
+	// Use checkout and a detached HEAD, because it will refuse to overwrite
+	// local changes, and warn if commits are being left behind, but will not
+	// mind if master is force-pushed upstream.
+	if err := repo.vcs().Checkout(commit); err != nil {
+		return "", fmt.Errorf("failed to checkout git repository: %v", err)
+	}
+	if err := repo.clean(installCmdCleanFlag); err != nil {
+		return "", err
+	}
+	if err := repo.applyPatches(patches); err != nil {
+		if newWorktree {
+			if rerr := removeTipWorktree(repo.dir); rerr != nil {
+				logger.Warnf("failed to remove %s: %v", repo.dir, rerr)
+			}
+		}
+		return "", err
+	}
+
+	bm, err := buildTip(repo, buildCacheKey(commit, patches, b.Variant.Env), b.Variant)
+	if err != nil {
+		return "", err
+	}
+
+	md := newInstallMetadata(name)
+	md.URL = GetGoSourceGitURL()
+	md.Git = &gitMetadata{
+		Commit:  commit,
+		Ref:     b.Ref,
+		Patches: patches,
+	}
+	md.Build = bm
+	if b.CL != "" {
+		md.URL = GetGoSourceUpstreamGitURL()
+		md.Git.CL = b.CL
+		md.Git.PatchSet = patchSet
+	}
+
+	if err := setInstalled(repo.dir, md); err != nil {
+		return "", err
+	}
+
+	return name, testTip(repo.dir, md, b.Variant.Env)
+}
+
+// testTip runs the test suite selected by the --test flag, if any, with the
+// tip build in dir and records the result in its metadata md. It returns an
+// error if the tests fail.
+func testTip(dir string, md installMetadata, env []string) error {
+	if installCmdTestFlag == "" {
+		return nil
+	}
+
+	tm, err := runTestSuite(dir, installCmdTestFlag, env)
+	if err != nil {
+		return err
+	}
+
+	md.Test = tm
+	if err := writeInstallMetadata(dir, md); err != nil {
+		return err
+	}
+
+	if !tm.Passed {
+		return fmt.Errorf("the %s test suite failed, the full test log is at %s", tm.Suite, tm.Log)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/owenthereal/goup/internal/git"
+)
+
+// newGitCheckout creates a repository in a temporary directory with a
+// commit for each content of the VERSION file, and returns the directory
+// and the commits.
+func newGitCheckout(t *testing.T, versions ...string) (string, []string) {
+	t.Helper()
+
+	if !git.Installed() {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=goup", "GIT_AUTHOR_EMAIL=goup@example.com",
+			"GIT_COMMITTER_NAME=goup", "GIT_COMMITTER_EMAIL=goup@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	run("init", "-q", "-b", "master")
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("/bin/\n/.goup-*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var commits []string
+	for _, v := range versions {
+		if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "-A")
+		run("commit", "-q", "-m", v)
+		commits = append(commits, run("rev-parse", "HEAD"))
+	}
+
+	return dir, commits
+}
+
+func TestBuiltTipAfterFailedBuild(t *testing.T) {
+	dir, commits := newGitCheckout(t, "x", "y")
+	x, y := commits[0], commits[1]
+	repo := tipRepo{dir: dir}
+
+	// x is checked out and built.
+	if err := repo.vcs().Checkout(x); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", goExe()), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeInstallMetadata(dir, installMetadata{Git: &gitMetadata{Commit: x}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := builtTip(repo, x, buildVariant{}); !ok {
+		t.Fatalf("builtTip(%s) = false after building it", x)
+	}
+
+	// Building y fails after checking it out, leaving bin/go of x.
+	if err := invalidateTipBuild(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.vcs().Checkout(y); err != nil {
+		t.Fatal(err)
+	}
+
+	// Installing x again must not skip the build.
+	if _, ok := builtTip(repo, x, buildVariant{}); ok {
+		t.Errorf("builtTip(%s) = true after a failed build of %s", x, y)
+	}
+
+	// Neither with metadata that still records x.
+	if err := writeInstallMetadata(dir, installMetadata{Git: &gitMetadata{Commit: x}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := builtTip(repo, x, buildVariant{}); ok {
+		t.Errorf("builtTip(%s) = true with %s checked out", x, y)
+	}
+}
+
+func TestParseTipBuild(t *testing.T) {
+	for _, tt := range []struct {
+		ver    string
+		want   tipBuild
+		wantOK bool
+	}{
+		{"tip", tipBuild{}, true},
+		{"gotip", tipBuild{}, true},
+		{"tip@master", tipBuild{Ref: "master"}, true},
+		{"gotip@release-branch.go1.22", tipBuild{Ref: "release-branch.go1.22"}, true},
+		{"tip@a3e2a7e", tipBuild{Ref: "a3e2a7e"}, true},
+		{"tip@", tipBuild{}, false},
+		{"tipx", tipBuild{}, false},
+		{"1.22.0", tipBuild{}, false},
+		{"go1.22.0", tipBuild{}, false},
+	} {
+		got, ok := parseTipBuild(tt.ver)
+		if ok != tt.wantOK || got.Ref != tt.want.Ref {
+			t.Errorf("parseTipBuild(%q) = %+v, %v, want %+v, %v", tt.ver, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestVersionName(t *testing.T) {
+	for _, tt := range []struct {
+		ver  string
+		want string
+	}{
+		{"1.22.0", "go1.22.0"},
+		{"go1.22.0", "go1.22.0"},
+		{"tip", tipVersion},
+		{"tip@master", "gotip-master"},
+		{"tip@release-branch.go1.22", "gotip-release-branch.go1.22"},
+		{"tip@refs/heads/dev.boringcrypto", "gotip-refs-heads-dev.boringcrypto"},
+		{"tip@../../etc", "gotip-..-..-etc"},
+		{"tip@a b\\c", "gotip-a-b-c"},
+		// Commits are shortened.
+		{"tip@a3e2a7e", "gotip-a3e2a7e"},
+		{"tip@a3e2a7e0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6", "gotip-a3e2a7e0b1c2"},
+	} {
+		got := versionName(tt.ver)
+		if got != tt.want {
+			t.Errorf("versionName(%q) = %q, want %q", tt.ver, got, tt.want)
+		}
+		if strings.ContainsAny(got, `/\`) {
+			t.Errorf("versionName(%q) = %q is not a single path element", tt.ver, got)
+		}
+	}
+}
+
+// writeTestPatch writes a patch file and returns its metadata.
+func writeTestPatch(t *testing.T, name, patch string) patchMetadata {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(patch), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return patchMetadata{File: file}
+}
+
+func TestApplyPatches(t *testing.T) {
+	dir, _ := newGitCheckout(t, "x")
+	repo := tipRepo{dir: dir}
+
+	add := writeTestPatch(t, "add.patch", `diff --git a/a.txt b/a.txt
+new file mode 100644
+--- /dev/null
++++ b/a.txt
+@@ -0,0 +1 @@
++1
+`)
+	// change depends on add.
+	change := writeTestPatch(t, "change.patch", `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-1
++2
+`)
+	ignore := writeTestPatch(t, "ignore.patch", `diff --git a/.gitignore b/.gitignore
+--- a/.gitignore
++++ b/.gitignore
+@@ -1,2 +1,3 @@
+ /bin/
+ /.goup-*
++/pkg/
+`)
+	broken := writeTestPatch(t, "broken.patch", `diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-1
++2
+`)
+
+	if err := repo.applyPatches([]patchMetadata{add, change}); err != nil {
+		t.Fatalf("applyPatches() of stacked patches: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(b) != "2\n" {
+		t.Errorf("a.txt = %q, %v, want %q", b, err, "2\n")
+	}
+
+	if err := repo.vcs().Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.vcs().Clean(goupFilesPattern); err != nil {
+		t.Fatal(err)
+	}
+
+	// The changes of the patches before a broken one are undone.
+	if err := repo.applyPatches([]patchMetadata{add, ignore, broken}); err == nil {
+		t.Fatal("applyPatches() with a broken patch succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("a.txt of the first patch is left: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err != nil || string(b) != "/bin/\n/.goup-*\n" {
+		t.Errorf(".gitignore = %q, %v, want it unchanged", b, err)
+	}
+}
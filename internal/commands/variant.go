@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// buildVariant is a named set of environment settings that Go is built
+// with, e.g. GOEXPERIMENT=boringcrypto or GOAMD64=v3. A variant is installed
+// next to the plain build of the same version, e.g. as
+// go1.22.3-boringcrypto.
+type buildVariant struct {
+	Name string
+	Env  []string
+}
+
+var (
+	variantNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._]*$`)
+	envKeyRe      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// installVariant returns the variant selected by the --variant and --env
+// flags.
+func installVariant() (buildVariant, error) {
+	v := buildVariant{
+		Name: installCmdVariantFlag,
+		Env:  installCmdEnvFlag,
+	}
+
+	if v.Name == "" {
+		if len(v.Env) > 0 {
+			return v, errors.New("building with --env requires a name for the build with --variant")
+		}
+		return v, nil
+	}
+
+	if !variantNameRe.MatchString(v.Name) {
+		return v, fmt.Errorf("invalid variant name %q, only letters, digits, dots and underscores are allowed", v.Name)
+	}
+	for _, kv := range v.Env {
+		k, _, ok := strings.Cut(kv, "=")
+		if !ok || !envKeyRe.MatchString(k) {
+			return v, fmt.Errorf("invalid environment setting %q, must be KEY=VALUE", kv)
+		}
+	}
+
+	return v, nil
+}
+
+// versionName returns the name of the version directory that the variant
+// of version is installed in.
+func (v buildVariant) versionName(version string) string {
+	if v.Name == "" {
+		return version
+	}
+	return version + "-" + v.Name
+}
+
+// builtWith reports whether the Go described by bm was built with the
+// settings of the variant.
+func (v buildVariant) builtWith(bm *buildMetadata) bool {
+	var env []string
+	if bm != nil {
+		env = bm.Env
+	}
+
+	return slices.Equal(env, v.Env)
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyCmdRepairFlag bool
+)
+
+func verifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify [VERSION]",
+		Short: "Verify the files of an installed Go",
+		Long: `Verify the files of an installed Go against the manifest recorded when
+it was unpacked, reporting modified, missing and extra files. If no version
+is provided, verify the default Go.`,
+		Example: `
+  goup verify
+  goup verify 1.15.2
+  goup verify --repair 1.15.2
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runVerify,
+	}
+
+	verifyCmd.PersistentFlags().BoolVar(&verifyCmdRepairFlag, "repair", false, "Re-extract the cached archive to repair the installation")
+
+	return verifyCmd
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	var ver string
+	if len(args) > 0 {
+		ver = versionName(args[0])
+	} else {
+		var err error
+		ver, err = currentGoVersion()
+		if err != nil {
+			return fmt.Errorf("no default Go is set: %v", err)
+		}
+	}
+
+	targetDir := goupVersionDir(ver)
+	if !checkInstalled(targetDir) {
+		return fmt.Errorf("Go version %s is not installed. Install it with `goup install`.", ver)
+	}
+
+	m, err := readManifest(targetDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s has no manifest to verify against; reinstall it with `goup install`", ver)
+		}
+		return err
+	}
+
+	report, err := checkManifest(targetDir, m)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range report.Modified {
+		fmt.Printf("modified: %s\n", f)
+	}
+	for _, f := range report.Missing {
+		fmt.Printf("missing:  %s\n", f)
+	}
+	for _, f := range report.Extra {
+		fmt.Printf("extra:    %s\n", f)
+	}
+
+	if report.Len() == 0 {
+		logger.Printf("%s: all %d files verified", ver, len(m.Files))
+		return nil
+	}
+
+	if !verifyCmdRepairFlag {
+		return fmt.Errorf("%s: %d problems found, run `goup verify --repair %s` to fix them", ver, report.Len(), strings.TrimPrefix(ver, "go"))
+	}
+
+	return repairInstall(targetDir, m, report)
+}
+
+// repairInstall re-extracts the cached archive of the version installed in
+// targetDir and removes the files that are not part of it.
+func repairInstall(targetDir string, m manifest, report manifestReport) error {
+	archiveFile := filepath.Join(targetDir, m.Archive)
+	if _, err := os.Stat(archiveFile); err != nil {
+		return fmt.Errorf("cached archive %v is not available, reinstall with `goup remove` and `goup install`: %v", archiveFile, err)
+	}
+
+	// The archive is in the version directory too and may be damaged
+	// like the files it restores.
+	md, mdErr := readInstallMetadata(targetDir)
+	if mdErr == nil && md.ArchiveSHA256 != "" {
+		if err := verifySHA256(archiveFile, md.ArchiveSHA256); err != nil {
+			return fmt.Errorf("not repairing with the cached archive, reinstall with `goup remove` and `goup install`: %v", err)
+		}
+	} else {
+		logger.Warnf("the checksum of %v is unknown, it is not verified", archiveFile)
+	}
+
+	// Remove modified files first, their permissions may no longer allow
+	// them to be overwritten.
+	for _, f := range report.Modified {
+		if err := os.Remove(filepath.Join(targetDir, filepath.FromSlash(f))); err != nil {
+			return err
+		}
+	}
+
+	logger.Printf("Unpacking %v ...", archiveFile)
+	files, err := unpackArchive(targetDir, archiveFile)
+	if err != nil {
+		return fmt.Errorf("extracting archive %v: %v", archiveFile, err)
+	}
+
+	for _, f := range report.Extra {
+		logger.Debugf("Removing %s", f)
+		if err := os.Remove(filepath.Join(targetDir, filepath.FromSlash(f))); err != nil {
+			return err
+		}
+	}
+
+	repaired := manifest{Archive: m.Archive, Files: files}
+
+	// Go built from source needs to be rebuilt to restore the build output.
+	if mdErr == nil && md.Build != nil {
+		version, err := sourceTreeVersion(targetDir)
+		if err != nil {
+			return err
+		}
+		bootstrap, err := ensureBootstrap(version)
+		if err != nil {
+			return err
+		}
+		if err := buildSource(targetDir, bootstrap, md.Build.Env); err != nil {
+			return err
+		}
+		if repaired, err = scanManifest(targetDir, m.Archive); err != nil {
+			return err
+		}
+	}
+
+	if err := writeManifest(targetDir, repaired); err != nil {
+		return err
+	}
+
+	logger.Printf("Success: repaired %d files in %v", report.Len(), targetDir)
+	return nil
+}
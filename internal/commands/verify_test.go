@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairInstallChecksArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := "go1.22.0.linux-amd64.tar.gz"
+	if err := os.WriteFile(filepath.Join(dir, archive), []byte("damaged"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	md := newInstallMetadata("go1.22.0")
+	md.ArchiveSHA256 = strings.Repeat("0", 64)
+	if err := writeInstallMetadata(dir, md); err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifest{Archive: archive}
+	err := repairInstall(dir, m, manifestReport{Modified: []string{"VERSION"}})
+	if err == nil || !strings.Contains(err.Error(), "not repairing") {
+		t.Fatalf("repairInstall() = %v, want an error for the damaged archive", err)
+	}
+	// Nothing is changed before the archive is verified.
+	if _, err := os.Stat(filepath.Join(dir, "VERSION")); err != nil {
+		t.Errorf("the modified file is removed: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/owenthereal/goup/internal/entity"
+)
+
+const (
+	// versionFile pins the Go version of a project, e.g. 1.22.3.
+	versionFile = ".go-version"
+	goModFile   = "go.mod"
+)
+
+// projectVersion is the Go version a project asks for.
+type projectVersion struct {
+	Version string
+	// File is the .go-version or go.mod file the version is read from.
+	File string
+}
+
+// versionName returns the name of the version directory of the project
+// version. Only release versions and tip builds are accepted, since the
+// version comes from the project and must not name a directory outside of
+// the goup home.
+func (pv projectVersion) versionName() (string, error) {
+	ver := pv.Version
+	if strings.ContainsAny(ver, `/\`) || strings.Contains(ver, "..") || (!entity.ValidVersion(ver) && !isTipVersion(ver)) {
+		return "", fmt.Errorf("invalid Go version %q in %s", ver, pv.File)
+	}
+
+	return versionName(ver), nil
+}
+
+// findProjectVersion returns the Go version of the project dir is in. It
+// looks for a .go-version file or a go.mod file with a toolchain directive
+// in dir and its parents, the nearest one wins.
+func findProjectVersion(dir string) (projectVersion, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return projectVersion{}, false, err
+	}
+
+	readers := []struct {
+		name string
+		read func(file string) (string, error)
+	}{
+		{versionFile, readVersionFile},
+		{goModFile, readGoModToolchain},
+	}
+
+	for {
+		for _, r := range readers {
+			file := filepath.Join(dir, r.name)
+			ver, err := r.read(file)
+			if err != nil && !os.IsNotExist(err) {
+				return projectVersion{}, false, err
+			}
+			if ver != "" {
+				return projectVersion{Version: ver, File: file}, true, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return projectVersion{}, false, nil
+		}
+		dir = parent
+	}
+}
+
+// readVersionFile returns the version in a .go-version file, the first line
+// that is not empty or a comment.
+func readVersionFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+
+	return "", s.Err()
+}
+
+// readGoModToolchain returns the version of the toolchain directive in a
+// go.mod file, e.g. go1.22.3 for toolchain go1.22.3. The toolchain default
+// means no version.
+func readGoModToolchain(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "toolchain" && fields[1] != "default" {
+			return fields[1], nil
+		}
+	}
+
+	return "", s.Err()
+}
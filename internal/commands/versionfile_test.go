@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFiles writes the files, relative to dir and slash-separated.
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadGoModToolchain(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		want    string
+	}{
+		{"module example.com/m\n\ngo 1.22.0\n\ntoolchain go1.22.3\n", "go1.22.3"},
+		{"module example.com/m\n\ngo 1.22.0\ntoolchain go1.23rc1 // for testing\n", "go1.23rc1"},
+		{"module example.com/m\n\ngo 1.22.0\n", ""},
+		{"module example.com/m\n\ntoolchain default\n", ""},
+		{"module example.com/m\n\n// toolchain go1.22.3\n", ""},
+		{"module example.com/m\n\nrequire example.com/toolchain v1.0.0\n", ""},
+	} {
+		file := filepath.Join(t.TempDir(), goModFile)
+		if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := readGoModToolchain(file); err != nil || got != tt.want {
+			t.Errorf("readGoModToolchain() of %q = %q, %v, want %q", tt.content, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindProjectVersion(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"a/" + versionFile:   "# pinned\n\n1.21.6\n",
+		"a/" + goModFile:     "module a\n\ntoolchain go1.22.3\n",
+		"a/b/" + goModFile:   "module b\n\ntoolchain go1.23.0\n",
+		"a/b/c/" + goModFile: "module c\n\ngo 1.22\n",
+		"a/b/c/d/.keep":      "",
+		"e/" + versionFile:   "\n",
+		"e/f/.keep":          "",
+	})
+
+	for _, tt := range []struct {
+		dir      string
+		want     projectVersion
+		wantFind bool
+	}{
+		// .go-version wins over go.mod in the same directory.
+		{"a", projectVersion{"1.21.6", filepath.Join(root, "a", versionFile)}, true},
+		{"a/b", projectVersion{"go1.23.0", filepath.Join(root, "a", "b", goModFile)}, true},
+		// A go.mod without a toolchain directive doesn't stop the search.
+		{"a/b/c/d", projectVersion{"go1.23.0", filepath.Join(root, "a", "b", goModFile)}, true},
+		// Neither does an empty .go-version file.
+		{"e/f", projectVersion{}, false},
+	} {
+		got, found, err := findProjectVersion(filepath.Join(root, filepath.FromSlash(tt.dir)))
+		if err != nil || found != tt.wantFind || got != tt.want {
+			t.Errorf("findProjectVersion(%s) = %+v, %v, %v, want %+v, %v", tt.dir, got, found, err, tt.want, tt.wantFind)
+		}
+	}
+}
+
+func TestProjectVersionName(t *testing.T) {
+	home := setTestGoupHome(t)
+
+	for _, tt := range []struct {
+		ver  string
+		want string
+	}{
+		{"1.22.3", "go1.22.3"},
+		{"go1.23rc1", "go1.23rc1"},
+		{"1.22", "go1.22"},
+		{"tip", tipVersion},
+		{"tip@master", "gotip-master"},
+		{"../../../tmp/evil", ""},
+		{"1.22.3/../../../../tmp/evil", ""},
+		{`1.22.3\..\evil`, ""},
+		{"tip@../../evil", ""},
+		{"tip@refs/heads/dev", ""},
+		{"1.22.3-evil", ""},
+		{"latest", ""},
+	} {
+		pv := projectVersion{Version: tt.ver, File: filepath.Join(home, "p", versionFile)}
+		got, err := pv.versionName()
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("versionName() of %q = %q, want an error", tt.ver, got)
+			} else if !strings.Contains(err.Error(), pv.File) {
+				t.Errorf("versionName() of %q = %v, want an error naming %s", tt.ver, err, pv.File)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("versionName() of %q = %q, %v, want %q", tt.ver, got, err, tt.want)
+		}
+		if dir := goupVersionDir(got); !isSubdir(home, dir) {
+			t.Errorf("the directory %s of %q is outside of the goup home", dir, tt.ver)
+		}
+	}
+}
+
+func TestHookExportRefusesInvalidVersion(t *testing.T) {
+	home := setTestGoupHome(t)
+	project := filepath.Join(home, "p")
+	evil := filepath.Join(home, "..", "evil")
+	writeTestFiles(t, project, map[string]string{versionFile: "../../evil\n"})
+	writeTestFiles(t, evil, map[string]string{"bin/go": ""})
+
+	t.Chdir(project)
+	t.Setenv(hookVersionEnv, "")
+	out, err := hookExport("bash")
+	if err == nil {
+		t.Errorf("hookExport() = %q, want an error for the invalid version", out)
+	}
+}
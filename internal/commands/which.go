@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// The reasons a Go version is selected, from the highest precedence.
+const (
+	// selectedByEnv is a GOROOT set in the environment, e.g. by goup exec.
+	selectedByEnv = "env"
+	// selectedByVersionFile is a .go-version file of the project.
+	selectedByVersionFile = "version-file"
+	// selectedByGoMod is the toolchain directive of go.mod of the project.
+	selectedByGoMod = "go.mod"
+	// selectedBySymlink is the current link set by goup set.
+	selectedBySymlink = "symlink"
+)
+
+var whichCmdJSONFlag bool
+
+// goSelection is the Go version that is used in the working directory and
+// why.
+type goSelection struct {
+	Version string `json:"version"`
+	GOROOT  string `json:"goroot"`
+	// Go is the path of the command, go unless another one is asked for.
+	// It is empty if the version is not installed.
+	Go     string `json:"go"`
+	Reason string `json:"reason"`
+	// Source is the file or environment variable the version is selected
+	// by.
+	Source    string `json:"source"`
+	Installed bool   `json:"installed"`
+	// Active reports whether the version is set up in the environment. A
+	// version selected by a project is only once goup hook switched to it.
+	Active bool `json:"active"`
+}
+
+func whichCmd() *cobra.Command {
+	whichCmd := &cobra.Command{
+		Use:     "which [COMMAND]",
+		Aliases: []string{"current"},
+		Short:   "Show the Go used in the working directory and why",
+		Long: `Print the Go version used in the working directory, the path of its go command,
+or of another command of it, and why it is selected. In order of precedence,
+it is selected by a GOROOT set in the environment, e.g. by goup exec, by the
+nearest .go-version file or toolchain directive in go.mod, as switched to by
+goup hook, or by the current link set by goup set. It fails if the version has
+no such command.`,
+		Example: `
+  goup which
+  goup which gofmt
+  goup current --json
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runWhich,
+	}
+
+	whichCmd.PersistentFlags().BoolVar(&whichCmdJSONFlag, "json", false, "print the version, paths and reason as JSON")
+
+	return whichCmd
+}
+
+func runWhich(cmd *cobra.Command, args []string) error {
+	command := "go"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	sel, err := selectGoVersion()
+	if err != nil {
+		return err
+	}
+	if sel.Installed {
+		exe := command
+		if runtime.GOOS == "windows" {
+			exe += ".exe"
+		}
+		sel.Go = filepath.Join(sel.GOROOT, "bin", exe)
+		if _, err := os.Stat(sel.Go); err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%s is not a command of Go %s in %s", command, sel.Version, filepath.Join(sel.GOROOT, "bin"))
+		}
+	}
+
+	if whichCmdJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sel)
+	}
+
+	fmt.Println(sel.Version)
+	if sel.Installed {
+		fmt.Println(sel.Go)
+	}
+	fmt.Println(sel.reason())
+
+	switch {
+	case !sel.Installed:
+		logger.Warnf("Go %s is not installed, install it with `goup install --no-set %s`", sel.Version, sel.Version)
+	case !sel.Active:
+		logger.Warnf("goup hook has not switched to Go %s in this shell, so the go in PATH is still the default Go", sel.Version)
+	}
+
+	return nil
+}
+
+// reason describes why the version is selected.
+func (s goSelection) reason() string {
+	switch s.Reason {
+	case selectedByEnv:
+		return fmt.Sprintf("selected by the %s environment variable", s.Source)
+	case selectedByVersionFile:
+		return fmt.Sprintf("selected by the version file %s", s.Source)
+	case selectedByGoMod:
+		return fmt.Sprintf("selected by the toolchain directive of %s", s.Source)
+	default:
+		return fmt.Sprintf("selected as the default Go by the link %s", s.Source)
+	}
+}
+
+// selectGoVersion returns the Go version used in the working directory.
+// Unlike currentGoVersion, it takes GOROOT overrides and the project
+// versions switched to by goup hook into account.
+func selectGoVersion() (goSelection, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return goSelection{}, err
+	}
+	pv, inProject, err := findProjectVersion(wd)
+	if err != nil {
+		return goSelection{}, err
+	}
+	var projectVer string
+	if inProject {
+		if projectVer, err = pv.versionName(); err != nil {
+			return goSelection{}, err
+		}
+	}
+
+	// GOROOT set by the env file or goup hook is not an override.
+	if goroot := os.Getenv("GOROOT"); goroot != "" {
+		goroot = filepath.Clean(goroot)
+		override := goroot != GoupCurrentDir()
+		if inProject && goroot == goupVersionDir(projectVer) {
+			override = false
+		}
+		if override {
+			sel := goSelection{GOROOT: goroot, Reason: selectedByEnv, Source: "GOROOT", Installed: isGoroot(goroot), Active: true}
+			if ver, err := readGorootVersion(goroot); err == nil {
+				sel.Version = strings.TrimPrefix(ver, "go")
+			} else if isGoupPathDir(filepath.Join(goroot, "bin")) {
+				sel.Version = strings.TrimPrefix(filepath.Base(goroot), "go")
+			}
+			return sel, nil
+		}
+	}
+
+	if inProject {
+		sel := goSelection{
+			Version: strings.TrimPrefix(projectVer, "go"),
+			GOROOT:  goupVersionDir(projectVer),
+			Reason:  selectedByVersionFile,
+			Source:  pv.File,
+			// The hook records the version it switched to.
+			Active: os.Getenv(hookVersionEnv) == projectVer,
+		}
+		if filepath.Base(pv.File) == goModFile {
+			sel.Reason = selectedByGoMod
+		}
+		sel.Installed = isGoroot(sel.GOROOT)
+		return sel, nil
+	}
+
+	ver, err := currentGoVersion()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return goSelection{}, fmt.Errorf("no default Go is set, install one with `goup install` or set one with `goup set`")
+		}
+		return goSelection{}, err
+	}
+
+	return goSelection{
+		Version:   strings.TrimPrefix(ver, "go"),
+		GOROOT:    goupVersionDir(ver),
+		Reason:    selectedBySymlink,
+		Source:    GoupCurrentDir(),
+		Installed: isGoroot(GoupCurrentDir()),
+		Active:    true,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelectGoVersionProject(t *testing.T) {
+	home := setTestGoupHome(t)
+	setTestSharedStore(t)
+	writeTestVersion(t, home, "go1.22.3")
+	t.Setenv("GOROOT", "")
+	t.Setenv(hookVersionEnv, "go1.22.3")
+
+	project := filepath.Join(home, "p")
+	writeTestFiles(t, project, map[string]string{versionFile: "1.22.3\n"})
+	t.Chdir(project)
+
+	sel, err := selectGoVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := goSelection{
+		Version:   "1.22.3",
+		GOROOT:    filepath.Join(home, "go1.22.3"),
+		Reason:    selectedByVersionFile,
+		Source:    filepath.Join(project, versionFile),
+		Installed: true,
+		Active:    true,
+	}
+	if sel != want {
+		t.Errorf("selectGoVersion() = %+v, want %+v", sel, want)
+	}
+
+	writeTestFiles(t, project, map[string]string{versionFile: "../../../tmp/evil\n"})
+	if sel, err := selectGoVersion(); err == nil || !strings.Contains(err.Error(), filepath.Join(project, versionFile)) {
+		t.Errorf("selectGoVersion() with an invalid version = %+v, %v, want an error naming the version file", sel, err)
+	}
+}
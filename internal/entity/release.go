@@ -3,8 +3,10 @@ package entity
 import (
 	"errors"
 	"fmt"
+	"os"
 	"runtime"
 	"sort"
+	"strings"
 )
 
 type Kind string
@@ -19,13 +21,14 @@ type Release struct {
 	Files   []File `json:"files"`
 }
 
+// ArchiveFile returns the archive of the release for the host platform.
 func (r Release) ArchiveFile() (file File, err error) {
-	goos := getOS()
-	arch := runtime.GOARCH
+	return r.ArchiveFileFor(HostPlatform())
+}
 
-	if goos == "linux" && arch == "arm" {
-		arch = "armv6l"
-	}
+// ArchiveFileFor returns the archive of the release for the platform p.
+func (r Release) ArchiveFileFor(p Platform) (file File, err error) {
+	goos, arch := NormalizeOS(p.OS), p.DownloadArch()
 
 	for _, f := range r.Files {
 		if f.Arch == arch && f.Os == goos && f.Kind == Archive {
@@ -37,10 +40,108 @@ func (r Release) ArchiveFile() (file File, err error) {
 	return
 }
 
+// SourceFile returns the source tarball of the release.
+func (r Release) SourceFile() (file File, err error) {
+	for _, f := range r.Files {
+		if f.Kind == Source {
+			file = f
+			return
+		}
+	}
+	err = fmt.Errorf("source archive of %s not found", r.Version)
+	return
+}
+
+// Platform is an operating system and architecture pair that Go releases
+// are published for.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// HostPlatform returns the platform goup is running on. The GOUP_GO_ARCH
+// environment variable overrides the architecture.
+func HostPlatform() Platform {
+	arch := os.Getenv("GOUP_GO_ARCH")
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+
+	return Platform{
+		OS:   getOS(),
+		Arch: NormalizeArch(arch),
+	}
+}
+
+// DownloadArch returns the architecture name the release index uses for
+// the platform. Go publishes a single armv6l archive for 32-bit ARM Linux.
+func (p Platform) DownloadArch() string {
+	arch := NormalizeArch(p.Arch)
+	if NormalizeOS(p.OS) == "linux" && arch == "arm" {
+		return "armv6l"
+	}
+	return arch
+}
+
+// String returns the platform in the os-arch form used by the archive file
+// names, e.g. linux-armv6l.
+func (p Platform) String() string {
+	return NormalizeOS(p.OS) + "-" + p.DownloadArch()
+}
+
 func getOS() string {
 	return runtime.GOOS
 }
 
+var osAliases = map[string]string{
+	"macos": "darwin",
+	"osx":   "darwin",
+	"win":   "windows",
+}
+
+// NormalizeOS maps common operating system names to the GOOS values used by
+// the release index.
+func NormalizeOS(goos string) string {
+	goos = strings.ToLower(goos)
+	if v, ok := osAliases[goos]; ok {
+		return v
+	}
+	return goos
+}
+
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"armv8":   "arm64",
+	// uname reports armv8l for a 32-bit userland on an ARMv8 CPU.
+	"armv8l":      "arm",
+	"i386":        "386",
+	"i486":        "386",
+	"i586":        "386",
+	"i686":        "386",
+	"x86":         "386",
+	"armv6":       "arm",
+	"armv6l":      "arm",
+	"armv7":       "arm",
+	"armv7l":      "arm",
+	"ppc64el":     "ppc64le",
+	"loongarch64": "loong64",
+}
+
+// NormalizeArch maps the architecture names reported by uname and used by
+// the release index, e.g. x86_64 or armv6l, to GOARCH values. Names that are
+// already GOARCH values, such as 386, ppc64le, s390x, riscv64 or loong64, are
+// returned as is.
+func NormalizeArch(arch string) string {
+	arch = strings.ToLower(arch)
+	if v, ok := archAliases[arch]; ok {
+		return v
+	}
+	return arch
+}
+
 type File struct {
 	Filename string `json:"filename"`
 	Os       string `json:"os"`
@@ -0,0 +1,53 @@
+package entity
+
+import "testing"
+
+func TestNormalizeOS(t *testing.T) {
+	for in, want := range map[string]string{
+		"linux":   "linux",
+		"Linux":   "linux",
+		"darwin":  "darwin",
+		"macos":   "darwin",
+		"MacOS":   "darwin",
+		"osx":     "darwin",
+		"win":     "windows",
+		"windows": "windows",
+		"freebsd": "freebsd",
+	} {
+		if got := NormalizeOS(in); got != want {
+			t.Errorf("NormalizeOS(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeArch(t *testing.T) {
+	for in, want := range map[string]string{
+		"amd64":       "amd64",
+		"x86_64":      "amd64",
+		"X86_64":      "amd64",
+		"x86-64":      "amd64",
+		"x64":         "amd64",
+		"arm64":       "arm64",
+		"aarch64":     "arm64",
+		"armv8":       "arm64",
+		"armv8l":      "arm",
+		"armv7l":      "arm",
+		"armv7":       "arm",
+		"armv6l":      "arm",
+		"armv6":       "arm",
+		"arm":         "arm",
+		"i386":        "386",
+		"i686":        "386",
+		"x86":         "386",
+		"386":         "386",
+		"ppc64el":     "ppc64le",
+		"ppc64le":     "ppc64le",
+		"loongarch64": "loong64",
+		"riscv64":     "riscv64",
+		"s390x":       "s390x",
+	} {
+		if got := NormalizeArch(in); got != want {
+			t.Errorf("NormalizeArch(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package entity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// goVersion is a parsed Go release version such as go1.22.3, go1.21rc2 or
+// go1.20beta1.
+type goVersion struct {
+	nums [3]int
+	// pre ranks the pre-release kind: beta < rc < final release.
+	pre    int
+	preNum int
+}
+
+const (
+	preBeta = iota
+	preRC
+	preFinal
+)
+
+func parseGoVersion(v string) (goVersion, bool) {
+	var gv goVersion
+
+	v = strings.TrimPrefix(v, "go")
+	num := v
+	gv.pre = preFinal
+	for _, p := range []struct {
+		sep  string
+		rank int
+	}{{"beta", preBeta}, {"rc", preRC}} {
+		if i := strings.Index(v, p.sep); i >= 0 {
+			n, err := strconv.Atoi(v[i+len(p.sep):])
+			if err != nil {
+				return gv, false
+			}
+			num, gv.pre, gv.preNum = v[:i], p.rank, n
+			break
+		}
+	}
+
+	parts := strings.Split(num, ".")
+	if len(parts) > len(gv.nums) {
+		return gv, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return gv, false
+		}
+		gv.nums[i] = n
+	}
+
+	return gv, true
+}
+
+// ValidVersion reports whether v is a Go release version, with or without
+// the "go" prefix.
+func ValidVersion(v string) bool {
+	_, ok := parseGoVersion(v)
+	return ok
+}
+
+// CompareVersions compares the Go release versions a and b, with or without
+// the "go" prefix. It returns -1, 0 or +1 if a is older than, the same as or
+// newer than b. Invalid versions are older than valid ones.
+func CompareVersions(a, b string) int {
+	va, okA := parseGoVersion(a)
+	vb, okB := parseGoVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := range va.nums {
+		if c := compareInt(va.nums[i], vb.nums[i]); c != 0 {
+			return c
+		}
+	}
+	if c := compareInt(va.pre, vb.pre); c != 0 {
+		return c
+	}
+	return compareInt(va.preNum, vb.preNum)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// MinimumBootstrapVersion returns the oldest Go release that can bootstrap
+// a build of Go version v from source, or "" if v is built with a C
+// toolchain instead. v may also be a development version such as go1.26.
+func MinimumBootstrapVersion(v string) string {
+	gv, ok := parseGoVersion(v)
+	if !ok || gv.nums[0] != 1 {
+		return ""
+	}
+
+	switch minor := gv.nums[1]; {
+	case minor < 5:
+		return ""
+	case minor < 20:
+		return "go1.4"
+	case minor < 22:
+		return "go1.17.13"
+	default:
+		// Since Go 1.22, each even release raises the minimum to the .6
+		// point release of the Go two minors before it, e.g. Go 1.22 and
+		// Go 1.23 require Go 1.20.6.
+		return fmt.Sprintf("go1.%d.6", minor-2-minor%2)
+	}
+}
+
+// BootstrapReleaseMinimum returns the oldest release to download for
+// bootstrapping version, given its minimum bootstrap version min. Go 1.4 is
+// hard to come by on today's platforms, so it is replaced by Go 1.17.13,
+// the newest release that still bootstraps everything before Go 1.20.
+func BootstrapReleaseMinimum(version, min string) string {
+	if min == "go1.4" && CompareVersions(version, "go1.17.13") > 0 {
+		return "go1.17.13"
+	}
+	return min
+}
+
+// MinorVersion returns the major and minor version of v, e.g. go1.22 for
+// go1.22.3 or go1.22rc1.
+func MinorVersion(v string) string {
+	gv, ok := parseGoVersion(v)
+	if !ok {
+		return v
+	}
+	return fmt.Sprintf("go%d.%d", gv.nums[0], gv.nums[1])
+}
@@ -0,0 +1,98 @@
+package entity
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"go1.22.3", "go1.22.3", 0},
+		{"1.22.3", "go1.22.3", 0},
+		{"go1.21", "go1.21.0", 0},
+		{"go1.22.3", "go1.22.10", -1},
+		{"go1.9", "go1.10", -1},
+		{"go1.21.0", "go1.20.14", 1},
+		{"go1.21beta1", "go1.21rc1", -1},
+		{"go1.21rc1", "go1.21rc2", -1},
+		{"go1.21rc2", "go1.21", -1},
+		{"go1.21rc2", "go1.21.0", -1},
+		{"go1.21beta2", "go1.21beta1", 1},
+		{"go1.20.14", "go1.21beta1", -1},
+		{"invalid", "go1.0", -1},
+		{"go1.0", "invalid", 1},
+	} {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValidVersion(t *testing.T) {
+	for v, want := range map[string]bool{
+		"go1.22.3":    true,
+		"1.22":        true,
+		"go1.21rc1":   true,
+		"go1.20beta1": true,
+		"go1.2.3.4":   false,
+		"go1.21rc":    false,
+		"gotip":       false,
+		"go1.x":       false,
+	} {
+		if got := ValidVersion(v); got != want {
+			t.Errorf("ValidVersion(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestMinimumBootstrapVersion(t *testing.T) {
+	for v, want := range map[string]string{
+		"go1.4.3":   "",
+		"go1.5":     "go1.4",
+		"go1.19.13": "go1.4",
+		"go1.20":    "go1.17.13",
+		"go1.21rc2": "go1.17.13",
+		"go1.22.0":  "go1.20.6",
+		"go1.23.4":  "go1.20.6",
+		"go1.24":    "go1.22.6",
+		"go1.25.1":  "go1.22.6",
+		"go1.26":    "go1.24.6",
+		"go2.0":     "",
+		"invalid":   "",
+	} {
+		if got := MinimumBootstrapVersion(v); got != want {
+			t.Errorf("MinimumBootstrapVersion(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestBootstrapReleaseMinimum(t *testing.T) {
+	for _, tt := range []struct {
+		version, min, want string
+	}{
+		{"go1.19.13", "go1.4", "go1.17.13"},
+		{"go1.18", "go1.4", "go1.17.13"},
+		// Go 1.17.13 can't bootstrap itself or older releases.
+		{"go1.17.13", "go1.4", "go1.4"},
+		{"go1.16", "go1.4", "go1.4"},
+		{"go1.20", "go1.17.13", "go1.17.13"},
+		{"go1.22.0", "go1.20.6", "go1.20.6"},
+	} {
+		if got := BootstrapReleaseMinimum(tt.version, tt.min); got != tt.want {
+			t.Errorf("BootstrapReleaseMinimum(%q, %q) = %q, want %q", tt.version, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestMinorVersion(t *testing.T) {
+	for v, want := range map[string]string{
+		"go1.22.3":  "go1.22",
+		"go1.22rc1": "go1.22",
+		"1.21":      "go1.21",
+		"gotip":     "gotip",
+	} {
+		if got := MinorVersion(v); got != want {
+			t.Errorf("MinorVersion(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
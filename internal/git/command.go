@@ -0,0 +1,134 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// commandRepo runs the git command.
+type commandRepo struct {
+	dir  string
+	opts Options
+}
+
+// NewCommandRepo returns the checkout in dir using the git command.
+func NewCommandRepo(dir string, opts Options) Repo {
+	return &commandRepo{dir: dir, opts: opts}
+}
+
+func (r *commandRepo) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	if len(r.opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.opts.Env...)
+	}
+	return cmd
+}
+
+func (r *commandRepo) run(args ...string) error {
+	cmd := r.command(args...)
+	cmd.Stdout = r.opts.Stdout
+	cmd.Stderr = r.opts.Stderr
+	return cmd.Run()
+}
+
+func (r *commandRepo) output(args ...string) ([]byte, error) {
+	cmd := r.command(args...)
+	cmd.Stderr = r.opts.Stderr
+	return cmd.Output()
+}
+
+func (r *commandRepo) Clone(url string, depth int) error {
+	args := []string{"clone"}
+	if depth > 0 {
+		args = append(args, "--depth="+strconv.Itoa(depth))
+	}
+	return r.run(append(args, url, r.dir)...)
+}
+
+func (r *commandRepo) AddRemote(name, url string) error {
+	return r.run("remote", "add", name, url)
+}
+
+func (r *commandRepo) ListRemote(remote string) ([]Ref, error) {
+	out, err := r.output("ls-remote", remote)
+	if err != nil {
+		return nil, err
+	}
+
+	// ls-remote outputs a number of lines like:
+	// 2621ba2c60d05ec0b9ef37cd71e45047b004cead	refs/changes/37/227037/1
+	var refs []Ref
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		hash, name, ok := strings.Cut(s.Text(), "\t")
+		if ok {
+			refs = append(refs, Ref{Name: name, Hash: hash})
+		}
+	}
+	return refs, s.Err()
+}
+
+func (r *commandRepo) Fetch(remote, ref string, depth int) (string, error) {
+	args := []string{"fetch"}
+	if depth > 0 {
+		args = append(args, "--depth="+strconv.Itoa(depth))
+	}
+	if err := r.run(append(args, remote, ref)...); err != nil {
+		return "", err
+	}
+
+	out, err := r.output("rev-parse", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the fetched commit: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *commandRepo) Checkout(commit string) error {
+	return r.run("-c", "advice.detachedHead=false", "checkout", commit)
+}
+
+func (r *commandRepo) Head() (string, error) {
+	out, err := r.output("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *commandRepo) Reset() error {
+	return r.run("reset", "-q", "--hard")
+}
+
+func (r *commandRepo) Clean(exclude string) error {
+	args := []string{"clean", "-q", "-f", "-d"}
+	if exclude != "" {
+		args = append(args, "-e", exclude)
+	}
+	return r.run(args...)
+}
+
+func (r *commandRepo) CleanIgnored() error {
+	return r.run("clean", "-q", "-f", "-d", "-X")
+}
+
+func (r *commandRepo) IgnoredFiles() ([]string, error) {
+	out, err := r.output("ls-files", "-z", "--others", "--ignored", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range bytes.Split(out, []byte{0}) {
+		if len(name) > 0 {
+			files = append(files, string(name))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,68 @@
+// Package git fetches and checks out git repositories, with the git command
+// if it is installed and with a pure Go implementation otherwise.
+package git
+
+import (
+	"io"
+	"os/exec"
+)
+
+// Repo is a git checkout in a directory.
+type Repo interface {
+	// Clone clones the repository at url into the directory, which must
+	// be empty. Only the latest depth commits are fetched if depth > 0.
+	Clone(url string, depth int) error
+	// AddRemote adds the remote name for the repository at url.
+	AddRemote(name, url string) error
+	// ListRemote lists the references of the remote.
+	ListRemote(remote string) ([]Ref, error)
+	// Fetch fetches the branch, tag, full reference name or full commit
+	// hash ref from the remote, and returns the commit it points to. Only
+	// the latest depth commits are fetched if depth > 0.
+	Fetch(remote, ref string, depth int) (string, error)
+	// Checkout checks out commit with a detached HEAD. It refuses to
+	// overwrite local changes.
+	Checkout(commit string) error
+	// Head returns the commit that is checked out.
+	Head() (string, error)
+	// Reset discards the local changes to tracked files.
+	Reset() error
+	// Clean removes the untracked files and directories that are not
+	// ignored, except those matching the gitignore pattern exclude.
+	Clean(exclude string) error
+	// CleanIgnored removes the ignored files and directories.
+	CleanIgnored() error
+	// IgnoredFiles returns the ignored files, slash-separated and relative
+	// to the directory.
+	IgnoredFiles() ([]string, error)
+}
+
+// Ref is a reference of a remote repository.
+type Ref struct {
+	Name string
+	Hash string
+}
+
+// Options configure how git runs.
+type Options struct {
+	// Stdout and Stderr receive the progress and the output of git.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env is added to the environment of the git command.
+	Env []string
+}
+
+// Installed reports whether the git command is installed.
+func Installed() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Open returns the checkout in dir, which uses the git command if it is
+// installed and a pure Go implementation otherwise.
+func Open(dir string, opts Options) Repo {
+	if Installed() {
+		return NewCommandRepo(dir, opts)
+	}
+	return NewGoRepo(dir, opts)
+}
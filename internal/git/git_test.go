@@ -0,0 +1,204 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newBareRepo creates a bare repository with a master branch of two
+// commits, an annotated tag of the first one and a Gerrit change ref on top
+// of the second one. It returns the URL of the repository and the commits.
+func newBareRepo(t *testing.T) (string, map[string]string) {
+	t.Helper()
+
+	if !Installed() {
+		// The file transport of go-git runs git-upload-pack as well.
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "bare.git")
+	work := filepath.Join(dir, "work")
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=goup", "GIT_AUTHOR_EMAIL=goup@example.com",
+			"GIT_COMMITTER_NAME=goup", "GIT_COMMITTER_EMAIL=goup@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(file, content, msg string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(work, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(work, "add", "-A")
+		git(work, "commit", "-q", "-m", msg)
+		return git(work, "rev-parse", "HEAD")
+	}
+
+	git(dir, "init", "-q", "--bare", "-b", "master", bare)
+	git(dir, "init", "-q", "-b", "master", work)
+	// Allow fetching commits by hash, as GitHub does.
+	git(bare, "config", "uploadpack.allowReachableSHA1InWant", "true")
+
+	commits := map[string]string{}
+	if err := os.WriteFile(filepath.Join(work, ".gitignore"), []byte("/bin/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commits["first"] = commit("VERSION", "first", "first")
+	git(work, "tag", "-a", "-m", "v1", "v1")
+	commits["master"] = commit("VERSION", "second", "second")
+	git(work, "push", "-q", bare, "master", "v1")
+
+	commits["change"] = commit("VERSION", "change", "change")
+	git(work, "push", "-q", bare, "HEAD:refs/changes/01/1001/2")
+
+	return "file://" + filepath.ToSlash(bare), commits
+}
+
+func readFile(t *testing.T, file string) string {
+	t.Helper()
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRepo(t *testing.T) {
+	for name, open := range map[string]func(string, Options) Repo{
+		"command": NewCommandRepo,
+		"go":      NewGoRepo,
+	} {
+		t.Run(name, func(t *testing.T) {
+			url, commits := newBareRepo(t)
+
+			dir := t.TempDir()
+			r := open(dir, Options{Env: []string{"GIT_CONFIG_GLOBAL=/dev/null"}})
+
+			if err := r.Clone(url, 1); err != nil {
+				t.Fatalf("Clone: %v", err)
+			}
+			if got := readFile(t, filepath.Join(dir, "VERSION")); got != "second" {
+				t.Errorf("VERSION after Clone = %q, want %q", got, "second")
+			}
+
+			if err := r.AddRemote("upstream", url); err != nil {
+				t.Fatalf("AddRemote: %v", err)
+			}
+			refs, err := r.ListRemote("upstream")
+			if err != nil {
+				t.Fatalf("ListRemote: %v", err)
+			}
+			if !reflect.DeepEqual(findRef(refs, "refs/changes/01/1001/2"), Ref{Name: "refs/changes/01/1001/2", Hash: commits["change"]}) {
+				t.Errorf("ListRemote = %v, want refs/changes/01/1001/2 at %s", refs, commits["change"])
+			}
+
+			for _, tt := range []struct {
+				remote, ref string
+				depth       int
+				want        string
+				content     string
+			}{
+				// An ancestor of the shallow clone is fetched though
+				// the server assumes that the clone has it.
+				{"origin", "v1", 0, commits["first"], "first"},
+				{"upstream", "refs/changes/01/1001/2", 0, commits["change"], "change"},
+				{"origin", "v1", 1, commits["first"], "first"},
+				{"origin", "master", 0, commits["master"], "second"},
+				{"origin", commits["first"], 1, commits["first"], "first"},
+			} {
+				commit, err := r.Fetch(tt.remote, tt.ref, tt.depth)
+				if err != nil {
+					t.Fatalf("Fetch(%s, %s): %v", tt.remote, tt.ref, err)
+				}
+				if commit != tt.want {
+					t.Errorf("Fetch(%s, %s) = %s, want %s", tt.remote, tt.ref, commit, tt.want)
+				}
+				if err := r.Checkout(commit); err != nil {
+					t.Fatalf("Checkout(%s): %v", commit, err)
+				}
+				if got := readFile(t, filepath.Join(dir, "VERSION")); got != tt.content {
+					t.Errorf("VERSION after checking out %s = %q, want %q", tt.ref, got, tt.content)
+				}
+				if head, err := r.Head(); err != nil || head != commit {
+					t.Errorf("Head after checking out %s = %s, %v, want %s", tt.ref, head, err, commit)
+				}
+			}
+
+			// Local changes are not overwritten by Checkout, but discarded
+			// by Reset.
+			if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte("local"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Checkout(commits["master"]); err == nil {
+				t.Errorf("Checkout with local changes succeeded")
+			}
+			if err := r.Reset(); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+			if got := readFile(t, filepath.Join(dir, "VERSION")); got != "first" {
+				t.Errorf("VERSION after Reset = %q, want %q", got, "first")
+			}
+
+			for _, f := range []string{"bin/go", "junk/file", ".goup-install.json"} {
+				if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(f)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ignored, err := r.IgnoredFiles()
+			if err != nil {
+				t.Fatalf("IgnoredFiles: %v", err)
+			}
+			if want := []string{"bin/go"}; !reflect.DeepEqual(ignored, want) {
+				t.Errorf("IgnoredFiles = %v, want %v", ignored, want)
+			}
+
+			if err := r.Clean("/.goup-*"); err != nil {
+				t.Fatalf("Clean: %v", err)
+			}
+			assertExist(t, dir, map[string]bool{"bin/go": true, "junk": false, ".goup-install.json": true})
+
+			if err := r.CleanIgnored(); err != nil {
+				t.Fatalf("CleanIgnored: %v", err)
+			}
+			assertExist(t, dir, map[string]bool{"bin": false, ".goup-install.json": true, "VERSION": true})
+		})
+	}
+}
+
+func findRef(refs []Ref, name string) Ref {
+	for _, ref := range refs {
+		if ref.Name == name {
+			return ref
+		}
+	}
+	return Ref{}
+}
+
+func assertExist(t *testing.T, dir string, files map[string]bool) {
+	t.Helper()
+	for f, want := range files {
+		_, err := os.Stat(filepath.Join(dir, f))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", f, got, want)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// fetchHead is the reference Fetch stores the fetched commit in, like
+// FETCH_HEAD of the git command.
+const fetchHead = plumbing.ReferenceName("refs/goup/fetch-head")
+
+var commitHashRe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// goRepo is a pure Go implementation of git.
+type goRepo struct {
+	dir  string
+	opts Options
+}
+
+// NewGoRepo returns the checkout in dir using a pure Go implementation of
+// git, which doesn't need the git command.
+func NewGoRepo(dir string, opts Options) Repo {
+	return &goRepo{dir: dir, opts: opts}
+}
+
+func (r *goRepo) open() (*gogit.Repository, error) {
+	return gogit.PlainOpen(r.dir)
+}
+
+func (r *goRepo) Clone(url string, depth int) error {
+	_, err := gogit.PlainClone(r.dir, false, &gogit.CloneOptions{
+		URL:          url,
+		Depth:        depth,
+		SingleBranch: true,
+		Tags:         gogit.NoTags,
+		Progress:     r.opts.Stdout,
+	})
+	return err
+}
+
+func (r *goRepo) AddRemote(name, url string) error {
+	repo, err := r.open()
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{url}})
+	return err
+}
+
+func (r *goRepo) ListRemote(remote string) ([]Ref, error) {
+	repo, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	rem, err := repo.Remote(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := rem.List(&gogit.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Ref
+	for _, ref := range list {
+		if ref.Type() == plumbing.HashReference {
+			refs = append(refs, Ref{Name: ref.Name().String(), Hash: ref.Hash().String()})
+		}
+	}
+	return refs, nil
+}
+
+func (r *goRepo) Fetch(remote, ref string, depth int) (string, error) {
+	repo, err := r.open()
+	if err != nil {
+		return "", err
+	}
+
+	src := ref
+	if !commitHashRe.MatchString(ref) {
+		refs, err := r.ListRemote(remote)
+		if err != nil {
+			return "", err
+		}
+		if src, err = resolveRef(refs, ref); err != nil {
+			return "", err
+		}
+	}
+
+	commit, err := r.fetch(repo, remote, src, depth)
+	if errors.Is(err, plumbing.ErrObjectNotFound) && depth == 0 {
+		// The server doesn't send the commits that are reachable from the
+		// shallow commits of a shallow clone, though they are missing, so
+		// fetch the commit itself.
+		if commit, err = r.fetch(repo, remote, src, 1); errors.Is(err, plumbing.ErrObjectNotFound) {
+			// The annotated tag that src points to is not fetched again.
+			commit, err = r.fetch(repo, remote, commit.String(), 1)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return commit.String(), nil
+}
+
+// fetch fetches src into fetchHead and returns the commit it points to,
+// which is returned with the error as well if it is missing.
+func (r *goRepo) fetch(repo *gogit.Repository, remote, src string, depth int) (plumbing.Hash, error) {
+	// go-git skips the fetch if fetchHead already points to the commit.
+	if err := repo.Storer.RemoveReference(fetchHead); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	err := repo.Fetch(&gogit.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + src + ":" + fetchHead.String())},
+		Depth:      depth,
+		Tags:       gogit.NoTags,
+		Progress:   r.opts.Stdout,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, err
+	}
+
+	head, err := repo.Reference(fetchHead, true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read the fetched commit: %v", err)
+	}
+
+	commit, err := peelCommit(repo, head.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := repo.CommitObject(commit); err != nil {
+		return commit, fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+	return commit, nil
+}
+
+// resolveRef returns the full name of the branch, tag or reference name in
+// refs, following the rules of git rev-parse.
+func resolveRef(refs []Ref, name string) (string, error) {
+	for _, candidate := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name} {
+		for _, ref := range refs {
+			if ref.Name == candidate {
+				return candidate, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("couldn't find remote ref %s", name)
+}
+
+// peelCommit returns the commit that h, a commit or an annotated tag,
+// points to.
+func peelCommit(repo *gogit.Repository, h plumbing.Hash) (plumbing.Hash, error) {
+	for {
+		tag, err := repo.TagObject(h)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return h, nil
+		}
+		if err != nil {
+			return h, err
+		}
+		if tag.TargetType != plumbing.CommitObject && tag.TargetType != plumbing.TagObject {
+			return h, fmt.Errorf("tag %s doesn't point to a commit", tag.Name)
+		}
+		h = tag.Target
+	}
+}
+
+func (r *goRepo) Checkout(commit string) error {
+	repo, err := r.open()
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	// Check for local changes first, go-git would move HEAD before
+	// refusing to overwrite them.
+	if err := checkUnmodified(wt); err != nil {
+		return err
+	}
+
+	return wt.Checkout(&gogit.CheckoutOptions{Hash: plumbing.NewHash(commit)})
+}
+
+func (r *goRepo) Head() (string, error) {
+	repo, err := r.open()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+func checkUnmodified(wt *gogit.Worktree) error {
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+
+	for file, s := range status {
+		if s.Worktree == gogit.Untracked {
+			continue
+		}
+		if s.Worktree != gogit.Unmodified || s.Staging != gogit.Unmodified {
+			return fmt.Errorf("your local changes to %s would be overwritten by checkout", file)
+		}
+	}
+	return nil
+}
+
+func (r *goRepo) Reset() error {
+	repo, err := r.open()
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	return wt.Reset(&gogit.ResetOptions{Commit: head.Hash(), Mode: gogit.HardReset})
+}
+
+func (r *goRepo) Clean(exclude string) error {
+	_, others, err := r.untracked()
+	if err != nil {
+		return err
+	}
+
+	var keep gitignore.Pattern
+	if exclude != "" {
+		keep = gitignore.ParsePattern(exclude, nil)
+	}
+
+	var files []string
+	for _, f := range others {
+		if keep != nil && keep.Match(strings.Split(f, "/"), false) == gitignore.Exclude {
+			continue
+		}
+		files = append(files, f)
+	}
+
+	return r.remove(files)
+}
+
+func (r *goRepo) CleanIgnored() error {
+	ignored, _, err := r.untracked()
+	if err != nil {
+		return err
+	}
+
+	return r.remove(ignored)
+}
+
+func (r *goRepo) IgnoredFiles() ([]string, error) {
+	ignored, _, err := r.untracked()
+	return ignored, err
+}
+
+// untracked returns the untracked files in the checkout that are ignored
+// and those that are not.
+func (r *goRepo) untracked() (ignored, others []string, err error) {
+	repo, err := r.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracked := make(map[string]bool, len(idx.Entries))
+	for _, e := range idx.Entries {
+		tracked[e.Name] = true
+	}
+
+	patterns, err := gitignore.ReadPatterns(osfs.New(r.dir), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := gitignore.NewMatcher(patterns)
+
+	// Everything below an ignored directory is ignored.
+	ignoredDirs := map[string]bool{}
+	err = filepath.WalkDir(r.dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(r.dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		parts := strings.Split(rel, "/")
+
+		if d.IsDir() {
+			if rel == gogit.GitDirName {
+				return filepath.SkipDir
+			}
+			if ignoredDirs[path.Dir(rel)] || m.Match(parts, true) {
+				ignoredDirs[rel] = true
+			}
+			return nil
+		}
+
+		switch {
+		case tracked[rel]:
+		case ignoredDirs[path.Dir(rel)] || m.Match(parts, false):
+			ignored = append(ignored, rel)
+		default:
+			others = append(others, rel)
+		}
+		return nil
+	})
+
+	return ignored, others, err
+}
+
+// remove removes the files and the directories that are empty afterwards.
+func (r *goRepo) remove(files []string) error {
+	dirs := map[string]bool{}
+	for _, f := range files {
+		if err := os.Remove(filepath.Join(r.dir, filepath.FromSlash(f))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for dir := path.Dir(f); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	// Remove the deepest directories first, removing a directory that
+	// isn't empty fails and is fine.
+	var sorted []string
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i], "/") > strings.Count(sorted[j], "/")
+	})
+	for _, dir := range sorted {
+		os.Remove(filepath.Join(r.dir, filepath.FromSlash(dir)))
+	}
+
+	return nil
+}
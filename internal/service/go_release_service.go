@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/owenthereal/goup/internal/entity"
@@ -123,3 +124,28 @@ func (svc *GoReleaseService) DownloadFile(destFile, fileUrl string) (err error)
 	pw.Update()
 	return
 }
+
+// Ping checks that the download page of the Go host can be reached within
+// timeout. It doesn't retry and doesn't log failures.
+func (svc *GoReleaseService) Ping(timeout time.Duration) error {
+	client := svc.client.Clone().
+		SetRetryCount(0).
+		SetTimeout(timeout).
+		SetLogger(discardLogger{})
+
+	resp, err := client.R().Head(fmt.Sprintf("https://%s/dl/", svc.goHost))
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccess() {
+		return errors.New(resp.Status())
+	}
+
+	return nil
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Errorf(format string, v ...interface{}) {}
+func (discardLogger) Warnf(format string, v ...interface{})  {}
+func (discardLogger) Debugf(format string, v ...interface{}) {}